@@ -13,6 +13,7 @@ import (
 	"go.firedancer.io/radiance/cmd/radiance/blockstore"
 	"go.firedancer.io/radiance/cmd/radiance/gossip"
 	"go.firedancer.io/radiance/cmd/radiance/replay"
+	"go.firedancer.io/radiance/cmd/radiance/sbpf"
 	"k8s.io/klog/v2"
 
 	// Load in instruction pretty-printing
@@ -34,6 +35,7 @@ func init() {
 		&blockstore.Cmd,
 		&gossip.Cmd,
 		&replay.Cmd,
+		&sbpf.Cmd,
 		&tpu_udp.Cmd,
 		&tpu_quic.Cmd,
 	)
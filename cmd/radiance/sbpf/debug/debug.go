@@ -0,0 +1,76 @@
+package debug
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/gdbstub"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+	"k8s.io/klog/v2"
+)
+
+var Cmd = cobra.Command{
+	Use:   "debug <program.so>",
+	Short: "Run an SBF program under a GDB remote stub",
+	Args:  cobra.ExactArgs(1),
+}
+
+var flags = Cmd.Flags()
+
+var (
+	flagListen = flags.String("listen", "localhost:1234", "GDB remote listen address")
+	flagData   = flags.BytesHex("data", nil, "Instruction data (hex)")
+	flagMaxCU  = flags.Int("max-cu", 1_400_000, "Compute unit limit")
+	flagHeap   = flags.Uint32("heap-size", sealevel.MinHeapFrameBytes, "Heap frame size in bytes")
+)
+
+func init() {
+	Cmd.Run = run
+}
+
+func run(_ *cobra.Command, args []string) {
+	buf, err := os.ReadFile(args[0])
+	if err != nil {
+		klog.Exit(err)
+	}
+	ld, err := loader.NewLoaderFromBytes(buf)
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+	program, err := ld.Load()
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+	if err := program.Verify(sealevel.Syscalls()); err != nil {
+		klog.Exitf("Program failed verification: %s", err)
+	}
+
+	if err := sealevel.CheckHeapFrame(*flagHeap); err != nil {
+		klog.Exitf("Invalid heap size %d: %s", *flagHeap, err)
+	}
+
+	params := sealevel.Params{Data: *flagData}
+	var input bytes.Buffer
+	params.Serialize(&input)
+
+	log := new(sealevel.LogCollector)
+	d := sbpf.NewDebugger(program, &sbpf.VMOpts{
+		HeapSize: int(*flagHeap),
+		Syscalls: sealevel.Syscalls(),
+		Costs:    sealevel.DefaultCosts(),
+		Context: &sealevel.Execution{
+			Log: log,
+		},
+		MaxCU: *flagMaxCU,
+		Input: input.Bytes(),
+	})
+	if err := gdbstub.ListenAndServe(*flagListen, d); err != nil {
+		klog.Exit(err)
+	}
+	for _, line := range log.Logs {
+		klog.Info(line)
+	}
+}
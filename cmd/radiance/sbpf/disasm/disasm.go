@@ -0,0 +1,120 @@
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+	"k8s.io/klog/v2"
+)
+
+var Cmd = cobra.Command{
+	Use:   "disasm <program.so>",
+	Short: "Disassemble an SBF program",
+	Args:  cobra.ExactArgs(1),
+}
+
+func init() {
+	Cmd.Run = run
+}
+
+func run(_ *cobra.Command, args []string) {
+	buf, err := os.ReadFile(args[0])
+	if err != nil {
+		klog.Exit(err)
+	}
+	ld, err := loader.NewLoaderFromBytes(buf)
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+	program, err := ld.Load()
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+
+	wr := bufio.NewWriter(os.Stdout)
+	defer wr.Flush()
+	if err := disassemble(wr, program, sealevel.Syscalls(), ld.Imports()); err != nil {
+		klog.Exit(err)
+	}
+}
+
+// disassemble writes the program text as assembly, annotated with symbol names.
+//
+// Syscall names are resolved via the registry, or the program's imports if unknown.
+func disassemble(wr io.Writer, p *sbpf.Program, syscalls sbpf.SyscallRegistry, imports map[uint32]string) error {
+	funcStarts := make(map[int64]struct{}, len(p.Funcs)+1)
+	funcStarts[int64(p.Entrypoint)] = struct{}{}
+	for _, pc := range p.Funcs {
+		funcStarts[pc] = struct{}{}
+	}
+	for pc := range p.FuncNames {
+		funcStarts[pc] = struct{}{}
+	}
+
+	insCount := int64(len(p.Text) / sbpf.SlotSize)
+	for pc := int64(0); pc < insCount; pc++ {
+		if _, ok := funcStarts[pc]; ok {
+			if _, err := fmt.Fprintf(wr, "\n%s:\n", sbpf.FuncName(p, pc)); err != nil {
+				return err
+			}
+		}
+
+		slot := sbpf.GetSlot(p.Text[pc*sbpf.SlotSize:])
+		var slot2 sbpf.Slot
+		long := sbpf.IsLongIns(slot.Op()) && pc+1 < insCount
+		if long {
+			slot2 = sbpf.GetSlot(p.Text[(pc+1)*sbpf.SlotSize:])
+		}
+
+		asm := sbpf.Disassemble(slot, slot2)
+		switch slot.Op() {
+		case sbpf.OpCall:
+			if p.Version.StaticSyscalls() && slot.Src() == 1 {
+				target := pc + int64(slot.Imm()) + 1
+				asm = fmt.Sprintf("call %s ; -> %d", sbpf.FuncName(p, target), target)
+			} else {
+				asm = "call " + callName(p, syscalls, imports, slot.Uimm())
+			}
+		case sbpf.OpJa,
+			sbpf.OpJeqImm, sbpf.OpJeqReg,
+			sbpf.OpJgtImm, sbpf.OpJgtReg,
+			sbpf.OpJgeImm, sbpf.OpJgeReg,
+			sbpf.OpJltImm, sbpf.OpJltReg,
+			sbpf.OpJleImm, sbpf.OpJleReg,
+			sbpf.OpJsetImm, sbpf.OpJsetReg,
+			sbpf.OpJneImm, sbpf.OpJneReg,
+			sbpf.OpJsgtImm, sbpf.OpJsgtReg,
+			sbpf.OpJsgeImm, sbpf.OpJsgeReg,
+			sbpf.OpJsltImm, sbpf.OpJsltReg,
+			sbpf.OpJsleImm, sbpf.OpJsleReg:
+			asm += fmt.Sprintf(" ; -> %d", pc+int64(slot.Off())+1)
+		}
+		if _, err := fmt.Fprintf(wr, "%8d: %s\n", pc, asm); err != nil {
+			return err
+		}
+		if long {
+			pc++
+		}
+	}
+	return nil
+}
+
+// callName resolves the immediate of a call instruction to a function or syscall name.
+func callName(p *sbpf.Program, syscalls sbpf.SyscallRegistry, imports map[uint32]string, imm uint32) string {
+	if pc, ok := p.Funcs[imm]; ok {
+		return sbpf.FuncName(p, pc)
+	}
+	if name, ok := syscalls.Name(imm); ok {
+		return name
+	}
+	if name, ok := imports[imm]; ok {
+		return name + " ; unknown syscall"
+	}
+	return fmt.Sprintf("%#08x ; unknown", imm)
+}
@@ -0,0 +1,50 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+)
+
+func disassembleFixture(t *testing.T, syscalls sbpf.SyscallRegistry) string {
+	ld, err := loader.NewLoaderFromBytes(fixtures.Load(t, "sbpf", "memcpy_and_memmove_test_matched.so"))
+	require.NoError(t, err)
+	program, err := ld.Load()
+	require.NoError(t, err)
+
+	var out strings.Builder
+	require.NoError(t, disassemble(&out, program, syscalls, ld.Imports()))
+	return out.String()
+}
+
+func TestDisassemble(t *testing.T) {
+	out := disassembleFixture(t, sealevel.Syscalls())
+	for _, line := range []string{
+		"\nentrypoint:\n       0: mov64 r1, 0x0\n",
+		"       8: lddw r2, 0x100000366\n      10: mov64 r3, 0xc\n",
+		"      11: call my_copy ; unknown syscall\n",
+		"      16: call memcmp\n",
+		"      21: jeq r0, 0x0, +2 ; -> 24\n",
+		"      28: call sol_log_\n",
+		"\nmemcmp:\n      31: mov64 r4, 0x8\n",
+		"      55: jne r3, 0x0, -10 ; -> 46\n",
+		"\nstrlen:\n      57: mov64 r0, 0x0\n",
+	} {
+		assert.Contains(t, out, line)
+	}
+}
+
+func TestDisassemble_Registry(t *testing.T) {
+	syscalls := sbpf.NewSyscallRegistry()
+	syscalls.Register("my_copy", sealevel.SyscallMemcpy)
+
+	out := disassembleFixture(t, syscalls)
+	assert.Contains(t, out, "      11: call my_copy\n")
+	assert.Contains(t, out, "      28: call sol_log_ ; unknown syscall\n")
+}
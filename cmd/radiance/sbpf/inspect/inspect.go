@@ -0,0 +1,178 @@
+package inspect
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+)
+
+var Cmd = cobra.Command{
+	Use:   "inspect <program.so>...",
+	Short: "Print ELF metadata of SBF programs",
+	Long: "Prints sections, symbols, syscalls and relocations of SBF programs.\n" +
+		"Outputs one YAML document or JSON line per program.",
+	Args: cobra.MinimumNArgs(1),
+}
+
+var flags = Cmd.Flags()
+
+var flagFormat = flags.String("format", "yaml", "Output format (yaml, json)")
+
+func init() {
+	Cmd.Run = run
+}
+
+func run(_ *cobra.Command, args []string) {
+	var encode func(v any) error
+	switch *flagFormat {
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		defer enc.Close()
+		encode = enc.Encode
+	case "json":
+		encode = json.NewEncoder(os.Stdout).Encode
+	default:
+		klog.Exitf("Unsupported format: %s", *flagFormat)
+	}
+
+	for _, path := range args {
+		doc, err := inspect(path)
+		if err != nil {
+			klog.Errorf("Failed to inspect %s: %s", path, err)
+			continue
+		}
+		if err := encode(doc); err != nil {
+			klog.Exit(err)
+		}
+	}
+
+	if klog.Stats.Error.Lines() > 0 {
+		os.Exit(1)
+	}
+}
+
+func inspect(path string) (*program, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ld, err := loader.NewLoaderFromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ld.Load(); err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(buf)
+	return newProgram(path, hex.EncodeToString(hash[:]), ld.Info(), sealevel.Syscalls()), nil
+}
+
+type program struct {
+	File       string         `json:"file" yaml:"file"`
+	SHA256     string         `json:"sha256" yaml:"sha256"`
+	Version    string         `json:"version" yaml:"version"`
+	Flags      hexInt         `json:"flags" yaml:"flags"`
+	Entrypoint entrypoint     `json:"entrypoint" yaml:"entrypoint"`
+	Text       text           `json:"text" yaml:"text"`
+	Sections   []section      `json:"sections" yaml:"sections"`
+	Exports    []symbol       `json:"exports" yaml:"exports"`
+	Imports    []symbol       `json:"imports" yaml:"imports"`
+	Syscalls   []symbol       `json:"syscalls" yaml:"syscalls"`
+	Relocs     map[string]int `json:"relocations" yaml:"relocations"`
+}
+
+type entrypoint struct {
+	Addr hexInt `json:"addr" yaml:"addr"`
+	PC   uint64 `json:"pc" yaml:"pc"`
+}
+
+type text struct {
+	Addr      hexInt `json:"addr" yaml:"addr"`
+	Size      uint64 `json:"size" yaml:"size"`
+	Slots     uint64 `json:"slots" yaml:"slots"`
+	Functions int    `json:"functions" yaml:"functions"`
+}
+
+type section struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Flags string `json:"flags" yaml:"flags"`
+	Addr  hexInt `json:"addr" yaml:"addr"`
+	End   hexInt `json:"end" yaml:"end"`
+	Off   hexInt `json:"offset" yaml:"offset"`
+	Size  uint64 `json:"size" yaml:"size"`
+}
+
+type symbol struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Hash hexInt `json:"hash" yaml:"hash"`
+	Addr hexInt `json:"addr,omitempty" yaml:"addr,omitempty"`
+}
+
+// hexInt is an integer printed in hex.
+type hexInt uint64
+
+func (h hexInt) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%#x", uint64(h))), nil
+}
+
+func newProgram(path string, hash string, info *loader.Info, syscalls sbpf.SyscallRegistry) *program {
+	p := &program{
+		File:    path,
+		SHA256:  hash,
+		Version: info.Version.String(),
+		Flags:   hexInt(info.Flags),
+		Entrypoint: entrypoint{
+			Addr: hexInt(info.EntryAddr),
+			PC:   info.Entrypoint,
+		},
+		Text: text{
+			Addr:      hexInt(info.TextAddr),
+			Size:      info.TextSize,
+			Slots:     info.TextSize / sbpf.SlotSize,
+			Functions: info.FuncCount,
+		},
+		Sections: make([]section, len(info.Sections)),
+		Exports:  newSymbols(info.Exports),
+		Imports:  newSymbols(info.Imports),
+		Syscalls: newSymbols(info.Syscalls),
+		Relocs:   make(map[string]int, len(info.Relocs)),
+	}
+	for i, sh := range info.Sections {
+		p.Sections[i] = section{
+			Name:  sh.Name,
+			Type:  sh.Type.String(),
+			Flags: sh.Flags.String(),
+			Addr:  hexInt(sh.Addr),
+			End:   hexInt(sh.Addr + sh.Size),
+			Off:   hexInt(sh.Off),
+			Size:  sh.Size,
+		}
+	}
+	for typ, n := range info.Relocs {
+		p.Relocs[typ.String()] = n
+	}
+	for i, sym := range p.Syscalls {
+		if sym.Name == "" {
+			p.Syscalls[i].Name, _ = syscalls.Name(uint32(sym.Hash))
+		}
+	}
+	return p
+}
+
+func newSymbols(syms []loader.SymbolInfo) []symbol {
+	out := make([]symbol, len(syms))
+	for i, sym := range syms {
+		out[i] = symbol{Name: sym.Name, Hash: hexInt(sym.Hash), Addr: hexInt(sym.Addr)}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+	"k8s.io/klog/v2"
+)
+
+var Cmd = cobra.Command{
+	Use:   "profile <program.so>",
+	Short: "Profile compute unit usage of an SBF program",
+	Long: "Runs an SBF program and writes a pprof profile of its compute unit usage.\n" +
+		"Inspect the output with `go tool pprof`.",
+	Args: cobra.ExactArgs(1),
+}
+
+var flags = Cmd.Flags()
+
+var (
+	flagOut   = flags.StringP("out", "o", "sbpf.pb.gz", "Output path of pprof profile")
+	flagData  = flags.BytesHex("data", nil, "Instruction data (hex)")
+	flagMaxCU = flags.Int("max-cu", 1_400_000, "Compute unit limit")
+	flagHeap  = flags.Uint32("heap-size", sealevel.MinHeapFrameBytes, "Heap frame size in bytes")
+)
+
+func init() {
+	Cmd.Run = run
+}
+
+func run(_ *cobra.Command, args []string) {
+	buf, err := os.ReadFile(args[0])
+	if err != nil {
+		klog.Exit(err)
+	}
+	ld, err := loader.NewLoaderFromBytes(buf)
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+	program, err := ld.Load()
+	if err != nil {
+		klog.Exitf("Failed to load program: %s", err)
+	}
+	if err := program.Verify(sealevel.Syscalls()); err != nil {
+		klog.Exitf("Program failed verification: %s", err)
+	}
+
+	if err := sealevel.CheckHeapFrame(*flagHeap); err != nil {
+		klog.Exitf("Invalid heap size %d: %s", *flagHeap, err)
+	}
+
+	params := sealevel.Params{Data: *flagData}
+	var input bytes.Buffer
+	params.Serialize(&input)
+
+	syscalls := sealevel.Syscalls()
+	prof := sbpf.NewProfiler(program)
+	prof.Syscalls = syscalls
+	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: int(*flagHeap),
+		Syscalls: syscalls,
+		Costs:    sealevel.DefaultCosts(),
+		Context: &sealevel.Execution{
+			Log: new(sealevel.LogCollector),
+		},
+		MaxCU:    *flagMaxCU,
+		Input:    input.Bytes(),
+		Profiler: prof,
+	})
+	res, err := interpreter.Run()
+	var exc *sbpf.Exception
+	if errors.As(err, &exc) {
+		klog.Warningf("Program failed: %s\n%s", err, exc.FormatStack())
+	} else if err != nil {
+		klog.Warningf("Program failed: %s", err)
+	} else if res.R0 != 0 {
+		klog.Warningf("Program returned error code %#x", res.R0)
+	}
+	klog.Infof("Program consumed %d of %d compute units (%d instructions)",
+		res.CUUsed, res.CUMax, res.Instructions)
+
+	f, err := os.Create(*flagOut)
+	if err != nil {
+		klog.Exit(err)
+	}
+	defer f.Close()
+	if err := prof.WritePprof(f); err != nil {
+		klog.Exitf("Failed to write profile: %s", err)
+	}
+
+	ins, cu := prof.Total()
+	fmt.Printf("%d instructions, %d CU\n", ins, cu)
+	stats := prof.FuncStats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return stats[names[i]] > stats[names[j]] })
+	for _, name := range names {
+		fmt.Printf("%10d  %s\n", stats[name], name)
+	}
+	klog.Infof("Wrote profile to %s", *flagOut)
+}
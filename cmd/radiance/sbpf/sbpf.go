@@ -0,0 +1,23 @@
+package sbpf
+
+import (
+	"github.com/spf13/cobra"
+	"go.firedancer.io/radiance/cmd/radiance/sbpf/debug"
+	"go.firedancer.io/radiance/cmd/radiance/sbpf/disasm"
+	"go.firedancer.io/radiance/cmd/radiance/sbpf/inspect"
+	"go.firedancer.io/radiance/cmd/radiance/sbpf/profile"
+)
+
+var Cmd = cobra.Command{
+	Use:   "sbpf",
+	Short: "Inspect Solana Bytecode Format programs",
+}
+
+func init() {
+	Cmd.AddCommand(
+		&debug.Cmd,
+		&disasm.Cmd,
+		&inspect.Cmd,
+		&profile.Cmd,
+	)
+}
@@ -0,0 +1,91 @@
+# Source of relative_call_debug.so: relative_call.so with DWARF debug info
+# for a fictional C source file.
+#
+# The debug sections were generated with
+#   llvm-mc -triple bpfel -filetype=obj -dwarf-version=4 relative_call_debug.s
+# relocated against the .text address of relative_call.so (0x1000),
+# and added to relative_call.so using llvm-objcopy --add-section.
+
+	.text
+	.file 1 "/src" "relative_call.c"
+	.globl syscall
+syscall:
+	.loc 1 3 0
+	r6 = r1
+	.loc 1 4 5
+	r1 = 720 ll
+	r2 = 8
+	call -1
+	.loc 1 5 5
+	r6 += 1
+	r0 = r6
+	.loc 1 6 0
+	exit
+.Lsyscall_end:
+	.globl entrypoint
+entrypoint:
+	.loc 1 9 5
+	r6 = *(u8 *)(r1 + 0)
+	.loc 1 10 5
+	r1 = 728 ll
+	r2 = 11
+	call -1
+	.loc 1 11 5
+	r1 = r6
+	call -15
+	.loc 1 12 0
+	exit
+.Lentrypoint_end:
+
+	.section .debug_abbrev,"",@progbits
+	.byte 1                     # DW_TAG_compile_unit
+	.byte 0x11
+	.byte 1                     # DW_CHILDREN_yes
+	.byte 0x25, 0x08            # DW_AT_producer, DW_FORM_string
+	.byte 0x13, 0x05            # DW_AT_language, DW_FORM_data2
+	.byte 0x03, 0x08            # DW_AT_name, DW_FORM_string
+	.byte 0x1b, 0x08            # DW_AT_comp_dir, DW_FORM_string
+	.byte 0x10, 0x17            # DW_AT_stmt_list, DW_FORM_sec_offset
+	.byte 0x11, 0x01            # DW_AT_low_pc, DW_FORM_addr
+	.byte 0x12, 0x06            # DW_AT_high_pc, DW_FORM_data4
+	.byte 0, 0
+	.byte 2                     # DW_TAG_subprogram
+	.byte 0x2e
+	.byte 0                     # DW_CHILDREN_no
+	.byte 0x03, 0x08            # DW_AT_name, DW_FORM_string
+	.byte 0x3a, 0x0b            # DW_AT_decl_file, DW_FORM_data1
+	.byte 0x3b, 0x0b            # DW_AT_decl_line, DW_FORM_data1
+	.byte 0x11, 0x01            # DW_AT_low_pc, DW_FORM_addr
+	.byte 0x12, 0x06            # DW_AT_high_pc, DW_FORM_data4
+	.byte 0x3f, 0x19            # DW_AT_external, DW_FORM_flag_present
+	.byte 0, 0
+	.byte 0
+
+	.section .debug_info,"",@progbits
+	.long .Linfo_end-.Linfo_start
+.Linfo_start:
+	.short 4                    # DWARF version
+	.long 0                     # .debug_abbrev offset
+	.byte 8                     # address size
+	.byte 1                     # DW_TAG_compile_unit
+	.asciz "handwritten"
+	.short 0x0c                 # DW_LANG_C99
+	.asciz "relative_call.c"
+	.asciz "/src"
+	.long 0                     # .debug_line offset
+	.quad syscall
+	.long .Lentrypoint_end-syscall
+	.byte 2                     # DW_TAG_subprogram
+	.asciz "syscall"
+	.byte 1
+	.byte 3
+	.quad syscall
+	.long .Lsyscall_end-syscall
+	.byte 2                     # DW_TAG_subprogram
+	.asciz "entrypoint"
+	.byte 1
+	.byte 8
+	.quad entrypoint
+	.long .Lentrypoint_end-entrypoint
+	.byte 0
+.Linfo_end:
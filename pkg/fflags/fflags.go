@@ -5,6 +5,8 @@
 package fflags
 
 import (
+	"encoding/binary"
+
 	"go.firedancer.io/radiance/pkg/solana"
 )
 
@@ -56,12 +58,20 @@ func (s *Features) set(idx uint, v uint) {
 	if bucket >= len(s.buckets) {
 		s.buckets = append(s.buckets, make([]uint32, bucket-len(s.buckets)+1)...)
 	}
-	s.buckets[bucket] |= 1 << (idx % 32)
+	if v != 0 {
+		s.buckets[bucket] |= 1 << (idx % 32)
+	} else {
+		s.buckets[bucket] &^= 1 << (idx % 32)
+	}
 }
 
 // HasFeature returns true if the given feature flag is set.
 func (s *Features) HasFeature(flag Feature) bool {
-	return s.buckets[uint(flag)/32]&(1<<(uint(flag)%32)) != 0
+	bucket := uint(flag) / 32
+	if s == nil || bucket >= uint(len(s.buckets)) {
+		return false
+	}
+	return s.buckets[bucket]&(1<<(uint(flag)%32)) != 0
 }
 
 // WithFeature modifies s to include the given feature flag.
@@ -85,3 +95,20 @@ func (s *Features) Clone() *Features {
 	copy(c.buckets, s.buckets)
 	return c
 }
+
+// Key returns a compact representation of s, suitable as a map key.
+// Sets with the same features return the same key.
+func (s *Features) Key() string {
+	if s == nil {
+		return ""
+	}
+	n := len(s.buckets)
+	for n > 0 && s.buckets[n-1] == 0 {
+		n--
+	}
+	key := make([]byte, 4*n)
+	for i, b := range s.buckets[:n] {
+		binary.LittleEndian.PutUint32(key[4*i:], b)
+	}
+	return string(key)
+}
@@ -50,6 +50,20 @@ type RentParams struct {
 	BurnPercent         uint8
 }
 
+// Clock is the cluster time as of the current slot.
+type Clock struct {
+	Slot                uint64
+	EpochStartTimestamp int64
+	Epoch               uint64
+	LeaderScheduleEpoch uint64
+	UnixTimestamp       int64
+}
+
+// Fees is the deprecated fee calculator of the current slot.
+type Fees struct {
+	LamportsPerSignature uint64
+}
+
 type Accounts interface {
 	GetAccount(pubkey *[32]byte) (*Account, error)
 	SetAccount(pubkey *[32]byte, acc *Account) error
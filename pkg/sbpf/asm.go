@@ -1,6 +1,12 @@
 package sbpf
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
 
 var mnemonicTable = [0x100]string{
 	OpLddw:      "lddw",
@@ -72,39 +78,68 @@ var mnemonicTable = [0x100]string{
 	OpArsh64Reg: "arsh64",
 	OpSdiv64Imm: "sdiv64",
 	OpSdiv64Reg: "sdiv64",
-	OpJa:        "ja",
-	OpJeqImm:    "jeq",
-	OpJeqReg:    "jeq",
-	OpJgtImm:    "jgt",
-	OpJgtReg:    "jgt",
-	OpJgeImm:    "jge",
-	OpJgeReg:    "jge",
-	OpJltImm:    "jlt",
-	OpJltReg:    "jlt",
-	OpJleImm:    "jle",
-	OpJleReg:    "jle",
-	OpJsetImm:   "jset",
-	OpJsetReg:   "jset",
-	OpJneImm:    "jne",
-	OpJneReg:    "jne",
-	OpJsgtImm:   "jsgt",
-	OpJsgtReg:   "jsgt",
-	OpJsgeImm:   "jsge",
-	OpJsgeReg:   "jsge",
-	OpJsltImm:   "jslt",
-	OpJsltReg:   "jslt",
-	OpJsleImm:   "jsle",
-	OpJsleReg:   "jsle",
-	OpCall:      "call",
-	OpCallx:     "callx",
-	OpExit:      "exit",
+
+	OpUhmul64Imm:   "uhmul64",
+	OpUhmul64Reg:   "uhmul64",
+	OpUdiv32Imm:    "udiv32",
+	OpUdiv32Reg:    "udiv32",
+	OpUdiv64Imm:    "udiv64",
+	OpUdiv64Reg:    "udiv64",
+	OpUrem32Imm:    "urem32",
+	OpUrem32Reg:    "urem32",
+	OpUrem64Imm:    "urem64",
+	OpUrem64Reg:    "urem64",
+	OpLmul32Imm:    "lmul32",
+	OpLmul32Reg:    "lmul32",
+	OpLmul64Imm:    "lmul64",
+	OpLmul64Reg:    "lmul64",
+	OpShmul64Imm:   "shmul64",
+	OpShmul64Reg:   "shmul64",
+	OpPqrSdiv32Imm: "sdiv32",
+	OpPqrSdiv32Reg: "sdiv32",
+	OpPqrSdiv64Imm: "sdiv64",
+	OpPqrSdiv64Reg: "sdiv64",
+	OpSrem32Imm:    "srem32",
+	OpSrem32Reg:    "srem32",
+	OpSrem64Imm:    "srem64",
+	OpSrem64Reg:    "srem64",
+
+	OpJa:      "ja",
+	OpJeqImm:  "jeq",
+	OpJeqReg:  "jeq",
+	OpJgtImm:  "jgt",
+	OpJgtReg:  "jgt",
+	OpJgeImm:  "jge",
+	OpJgeReg:  "jge",
+	OpJltImm:  "jlt",
+	OpJltReg:  "jlt",
+	OpJleImm:  "jle",
+	OpJleReg:  "jle",
+	OpJsetImm: "jset",
+	OpJsetReg: "jset",
+	OpJneImm:  "jne",
+	OpJneReg:  "jne",
+	OpJsgtImm: "jsgt",
+	OpJsgtReg: "jsgt",
+	OpJsgeImm: "jsge",
+	OpJsgeReg: "jsge",
+	OpJsltImm: "jslt",
+	OpJsltReg: "jslt",
+	OpJsleImm: "jsle",
+	OpJsleReg: "jsle",
+	OpCall:    "call",
+	OpCallx:   "callx",
+	OpExit:    "exit",
 }
 
 func GetOpcodeName(opc uint8) string {
 	return mnemonicTable[opc]
 }
 
-func disassemble(slot Slot, slot2 Slot) string {
+// Disassemble returns the assembly text of an instruction.
+//
+// The second slot is only used by instructions spanning two slots (lddw).
+func Disassemble(slot Slot, slot2 Slot) string {
 	opc := slot.Op()
 	mnemonic := GetOpcodeName(opc)
 	switch opc {
@@ -113,15 +148,15 @@ func disassemble(slot Slot, slot2 Slot) string {
 	case OpLdxb, OpLdxh, OpLdxw, OpLdxdw:
 		return fmt.Sprintf("%s r%d, [r%d%#+x]", mnemonic, slot.Dst(), slot.Src(), slot.Off())
 	case OpStb:
-		return fmt.Sprintf("stb [r%d%#+x], %#x", slot.Src(), slot.Off(), int8(slot.Imm()))
+		return fmt.Sprintf("stb [r%d%#+x], %#x", slot.Dst(), slot.Off(), int8(slot.Imm()))
 	case OpSth:
-		return fmt.Sprintf("sth [r%d%#+x], %#x", slot.Src(), slot.Off(), int16(slot.Imm()))
+		return fmt.Sprintf("sth [r%d%#+x], %#x", slot.Dst(), slot.Off(), int16(slot.Imm()))
 	case OpStw:
-		return fmt.Sprintf("stw [r%d%#+x], %#x", slot.Src(), slot.Off(), slot.Imm())
+		return fmt.Sprintf("stw [r%d%#+x], %#x", slot.Dst(), slot.Off(), slot.Imm())
 	case OpStdw:
-		return fmt.Sprintf("stdw [r%d%#+x], %#x", slot.Src(), slot.Off(), int64(slot.Imm()))
+		return fmt.Sprintf("stdw [r%d%#+x], %#x", slot.Dst(), slot.Off(), int64(slot.Imm()))
 	case OpStxb, OpStxh, OpStxw, OpStxdw:
-		return fmt.Sprintf("%s [r%d%#+x], r%d", mnemonic, slot.Src(), slot.Off(), slot.Dst())
+		return fmt.Sprintf("%s [r%d%#+x], r%d", mnemonic, slot.Dst(), slot.Off(), slot.Src())
 	case OpAdd32Imm, OpSub32Imm, OpAdd64Imm, OpSub64Imm:
 		return fmt.Sprintf("%s r%d, %#x", mnemonic, slot.Dst(), slot.Imm())
 	case OpOr32Imm, OpAnd32Imm, OpXor32Imm, OpMov32Imm:
@@ -129,12 +164,16 @@ func disassemble(slot Slot, slot2 Slot) string {
 	case OpDiv32Imm, OpMod32Imm, OpLsh32Imm, OpRsh32Imm, OpArsh32Imm,
 		OpDiv64Imm, OpMod64Imm, OpLsh64Imm, OpRsh64Imm, OpArsh64Imm:
 		return fmt.Sprintf("%s r%d, %d", mnemonic, slot.Dst(), slot.Uimm())
-	case OpMul32Imm, OpSdiv32Imm, OpMul64Imm, OpSdiv64Imm:
+	case OpMul32Imm, OpSdiv32Imm, OpMul64Imm, OpSdiv64Imm,
+		OpLmul32Imm, OpLmul64Imm, OpShmul64Imm, OpPqrSdiv32Imm, OpPqrSdiv64Imm, OpSrem32Imm, OpSrem64Imm:
 		return fmt.Sprintf("%s r%d, %d", mnemonic, slot.Dst(), slot.Imm())
+	case OpUhmul64Imm, OpUdiv32Imm, OpUdiv64Imm, OpUrem32Imm, OpUrem64Imm:
+		return fmt.Sprintf("%s r%d, %d", mnemonic, slot.Dst(), slot.Uimm())
 	case OpOr64Imm, OpAnd64Imm, OpXor64Imm, OpMov64Imm:
 		return fmt.Sprintf("%s r%d, %#x", mnemonic, slot.Dst(), uint64(slot.Imm()))
 	case OpAdd32Reg, OpSub32Reg, OpMul32Reg, OpDiv32Reg, OpOr32Reg, OpAnd32Reg, OpLsh32Reg, OpRsh32Reg, OpMod32Reg, OpXor32Reg, OpMov32Reg, OpArsh32Reg, OpSdiv32Reg,
-		OpAdd64Reg, OpSub64Reg, OpMul64Reg, OpDiv64Reg, OpOr64Reg, OpAnd64Reg, OpLsh64Reg, OpRsh64Reg, OpMod64Reg, OpXor64Reg, OpMov64Reg, OpArsh64Reg, OpSdiv64Reg:
+		OpAdd64Reg, OpSub64Reg, OpMul64Reg, OpDiv64Reg, OpOr64Reg, OpAnd64Reg, OpLsh64Reg, OpRsh64Reg, OpMod64Reg, OpXor64Reg, OpMov64Reg, OpArsh64Reg, OpSdiv64Reg,
+		OpUhmul64Reg, OpUdiv32Reg, OpUdiv64Reg, OpUrem32Reg, OpUrem64Reg, OpLmul32Reg, OpLmul64Reg, OpShmul64Reg, OpPqrSdiv32Reg, OpPqrSdiv64Reg, OpSrem32Reg, OpSrem64Reg:
 		return fmt.Sprintf("%s r%d, r%d", mnemonic, slot.Dst(), slot.Src())
 	case OpNeg32, OpNeg64:
 		return fmt.Sprintf("%s r%d", mnemonic, slot.Dst())
@@ -149,10 +188,483 @@ func disassemble(slot Slot, slot2 Slot) string {
 	case OpCall:
 		return fmt.Sprintf("call %#x", slot.Uimm())
 	case OpCallx:
-		return fmt.Sprintf("call r%d", slot.Dst())
+		if slot.Uimm() == 0 && slot.Src() != 0 {
+			// SBFv2 encodes the target register in the src field
+			return fmt.Sprintf("call r%d", slot.Src())
+		}
+		return fmt.Sprintf("call r%d", slot.Uimm())
 	case OpExit:
 		return "exit"
 	default:
 		return "invalid"
 	}
 }
+
+// Assemble parses SBF assembly and returns a program.
+//
+// The syntax matches the output of the disassembler.
+// Additionally, the assembler supports
+//   - labels (`name:`) on their own line or preceding an instruction,
+//   - jumps to labels (`ja loop`, `jne r1, 0x0, loop`),
+//   - calls by symbol name (`call name`),
+//   - comments starting with `;` or `//`.
+//
+// Calls to labels are emitted as internal function calls.
+// Calls to any other symbol name are emitted as syscalls
+// using the murmur3 hash of the name.
+// Execution starts at the `entrypoint` label if present, otherwise at the first instruction.
+func Assemble(src string) (*Program, error) {
+	return AssembleVersion(src, VersionV1)
+}
+
+// AssembleVersion is like Assemble, but targets the given SBF version.
+//
+// Since SBFv2, calls to labels use relative offsets,
+// and sdiv32/sdiv64 refer to the product/quotient/remainder class.
+func AssembleVersion(src string, version Version) (*Program, error) {
+	a := assembler{version: version}
+	if err := a.scan(src); err != nil {
+		return nil, err
+	}
+	return a.emit()
+}
+
+// asmLine is a tokenized source line containing an instruction.
+type asmLine struct {
+	num      int      // line number
+	pc       int64    // instruction slot
+	mnemonic string   // opcode name
+	operands []string // comma-separated operands
+}
+
+type assembler struct {
+	version Version
+	lines   []asmLine
+	labels  map[string]int64
+}
+
+// scan tokenizes the source and assigns a PC to each label and instruction.
+func (a *assembler) scan(src string) error {
+	a.labels = make(map[string]int64)
+	pc := int64(0)
+	for i, line := range strings.Split(src, "\n") {
+		num := i + 1
+		if idx := strings.IndexByte(line, ';'); idx >= 0 {
+			line = line[:idx]
+		}
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		// Labels
+		for {
+			idx := strings.IndexByte(line, ':')
+			if idx < 0 {
+				break
+			}
+			label := strings.TrimSpace(line[:idx])
+			if !isAsmIdent(label) {
+				return fmt.Errorf("line %d: invalid label %q", num, label)
+			}
+			if _, ok := a.labels[label]; ok {
+				return fmt.Errorf("line %d: duplicate label %q", num, label)
+			}
+			a.labels[label] = pc
+			line = strings.TrimSpace(line[idx+1:])
+		}
+		if line == "" {
+			continue
+		}
+
+		// Instruction
+		mnemonic, rest, _ := strings.Cut(line, " ")
+		ins := asmLine{
+			num:      num,
+			pc:       pc,
+			mnemonic: strings.ToLower(mnemonic),
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			for _, operand := range strings.Split(rest, ",") {
+				ins.operands = append(ins.operands, strings.TrimSpace(operand))
+			}
+		}
+		a.lines = append(a.lines, ins)
+		if ins.mnemonic == "lddw" {
+			pc += 2
+		} else {
+			pc++
+		}
+	}
+	return nil
+}
+
+// emit encodes all instructions.
+func (a *assembler) emit() (*Program, error) {
+	if len(a.lines) == 0 {
+		return nil, fmt.Errorf("empty program")
+	}
+	last := a.lines[len(a.lines)-1]
+	text := make([]byte, 0, (last.pc+2)*SlotSize)
+	funcs := make(map[uint32]int64)
+	for i := range a.lines {
+		line := &a.lines[i]
+		slots, err := a.encode(line, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line.num, line.mnemonic, err)
+		}
+		for _, slot := range slots {
+			text = binary.LittleEndian.AppendUint64(text, uint64(slot))
+		}
+	}
+	var entry int64
+	if pc, ok := a.labels["entrypoint"]; ok {
+		entry = pc
+	}
+	if entry*SlotSize >= int64(len(text)) {
+		return nil, fmt.Errorf("entrypoint out of bounds")
+	}
+	return &Program{
+		Version:    a.version,
+		RO:         text,
+		Text:       text,
+		TextVA:     VaddrProgram,
+		Entrypoint: uint64(entry),
+		Funcs:      funcs,
+	}, nil
+}
+
+// mnemonicOps maps each mnemonic to its immediate and register opcode variants.
+//
+// The product/quotient/remainder class is kept separately in pqrMnemonicOps,
+// as some of its mnemonics clash with the ALU class.
+var mnemonicOps, pqrMnemonicOps = buildMnemonicOps(false), buildMnemonicOps(true)
+
+func buildMnemonicOps(pqr bool) map[string][2]uint8 {
+	ops := make(map[string][2]uint8)
+	for op, mnemonic := range mnemonicTable {
+		if mnemonic == "" || (uint8(op)&0x07 == ClassPqr) != pqr {
+			continue
+		}
+		// Register variants share the mnemonic with the immediate variant
+		variants := ops[mnemonic]
+		if op&int(SrcX) != 0 && mnemonicTable[op&^int(SrcX)] == mnemonic {
+			variants[1] = uint8(op)
+		} else {
+			variants[0] = uint8(op)
+		}
+		ops[mnemonic] = variants
+	}
+	return ops
+}
+
+// lookupMnemonic returns the opcode variants of a mnemonic.
+func (a *assembler) lookupMnemonic(mnemonic string) (variants [2]uint8, ok bool) {
+	first, second := mnemonicOps, pqrMnemonicOps
+	if a.version.EnablePQR() {
+		first, second = second, first
+	}
+	if variants, ok = first[mnemonic]; ok {
+		return
+	}
+	variants, ok = second[mnemonic]
+	return
+}
+
+func (a *assembler) encode(line *asmLine, funcs map[uint32]int64) ([]Slot, error) {
+	mnemonic, ops := line.mnemonic, line.operands
+
+	// Byte swaps carry their size in the mnemonic
+	if mnemonic == "le" || mnemonic == "be" {
+		return nil, fmt.Errorf("missing size in %q", mnemonic)
+	}
+	for _, prefix := range [...]string{"le", "be"} {
+		if bits := strings.TrimPrefix(mnemonic, prefix); bits != mnemonic && bits != "" {
+			if err := expectOperands(ops, 1); err != nil {
+				return nil, err
+			}
+			dst, err := parseReg(ops[0])
+			if err != nil {
+				return nil, err
+			}
+			size, err := strconv.ParseUint(bits, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid size %q", bits)
+			}
+			op := OpLe
+			if prefix == "be" {
+				op = OpBe
+			}
+			return []Slot{makeSlot(op, dst, 0, 0, uint32(size))}, nil
+		}
+	}
+
+	variants, ok := a.lookupMnemonic(mnemonic)
+	if !ok {
+		return nil, fmt.Errorf("unknown mnemonic")
+	}
+	op := variants[0]
+
+	switch op {
+	case OpLddw:
+		if err := expectOperands(ops, 2); err != nil {
+			return nil, err
+		}
+		dst, err := parseReg(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		imm, err := parseInt(ops[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{
+			makeSlot(OpLddw, dst, 0, 0, uint32(imm)),
+			makeSlot(0, 0, 0, 0, uint32(imm>>32)),
+		}, nil
+	case OpLdxb, OpLdxh, OpLdxw, OpLdxdw:
+		if err := expectOperands(ops, 2); err != nil {
+			return nil, err
+		}
+		dst, err := parseReg(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		src, off, err := parseMem(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, src, off, 0)}, nil
+	case OpStb, OpSth, OpStw, OpStdw:
+		if err := expectOperands(ops, 2); err != nil {
+			return nil, err
+		}
+		dst, off, err := parseMem(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if src, err := parseReg(ops[1]); err == nil {
+			// Store from register uses the stx variant of the same size
+			return []Slot{makeSlot(op|ClassStx, dst, src, off, 0)}, nil
+		}
+		imm, err := parseImm(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, 0, off, imm)}, nil
+	case OpStxb, OpStxh, OpStxw, OpStxdw:
+		if err := expectOperands(ops, 2); err != nil {
+			return nil, err
+		}
+		dst, off, err := parseMem(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		src, err := parseReg(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, src, off, 0)}, nil
+	case OpNeg32, OpNeg64:
+		if err := expectOperands(ops, 1); err != nil {
+			return nil, err
+		}
+		dst, err := parseReg(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, 0, 0, 0)}, nil
+	case OpJa:
+		if err := expectOperands(ops, 1); err != nil {
+			return nil, err
+		}
+		off, err := a.parseTarget(line, ops[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, 0, 0, off, 0)}, nil
+	case OpCall, OpCallx:
+		if err := expectOperands(ops, 1); err != nil {
+			return nil, err
+		}
+		if reg, err := parseReg(ops[0]); err == nil {
+			if a.version.CallxUsesSrcReg() {
+				return []Slot{makeSlot(OpCallx, 0, reg, 0, 0)}, nil
+			}
+			return []Slot{makeSlot(OpCallx, 0, 0, 0, uint32(reg))}, nil
+		}
+		if target, ok := a.labels[ops[0]]; ok {
+			hash := PCHash(uint64(target))
+			funcs[hash] = target
+			if a.version.StaticSyscalls() {
+				return []Slot{makeSlot(op, 0, 1, 0, uint32(target-line.pc-1))}, nil
+			}
+			return []Slot{makeSlot(op, 0, 1, 0, hash)}, nil
+		}
+		if isAsmIdent(ops[0]) {
+			return []Slot{makeSlot(op, 0, 0, 0, SymbolHash(ops[0]))}, nil
+		}
+		imm, err := parseImm(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, 0, 0, 0, imm)}, nil
+	case OpExit:
+		if err := expectOperands(ops, 0); err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, 0, 0, 0, 0)}, nil
+	}
+
+	switch op & 0x07 {
+	case ClassAlu, ClassAlu64, ClassPqr:
+		if err := expectOperands(ops, 2); err != nil {
+			return nil, err
+		}
+		dst, err := parseReg(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if src, err := parseReg(ops[1]); err == nil {
+			return []Slot{makeSlot(variants[1], dst, src, 0, 0)}, nil
+		}
+		imm, err := parseImm(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, 0, 0, imm)}, nil
+	case ClassJmp:
+		if err := expectOperands(ops, 3); err != nil {
+			return nil, err
+		}
+		dst, err := parseReg(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		off, err := a.parseTarget(line, ops[2])
+		if err != nil {
+			return nil, err
+		}
+		if src, err := parseReg(ops[1]); err == nil {
+			return []Slot{makeSlot(variants[1], dst, src, off, 0)}, nil
+		}
+		imm, err := parseImm(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []Slot{makeSlot(op, dst, 0, off, imm)}, nil
+	}
+	return nil, fmt.Errorf("unsupported instruction")
+}
+
+// parseTarget parses a jump target (label or relative offset) into an offset field.
+func (a *assembler) parseTarget(line *asmLine, s string) (int16, error) {
+	var off int64
+	if target, ok := a.labels[s]; ok {
+		off = target - line.pc - 1
+	} else if isAsmIdent(s) {
+		return 0, fmt.Errorf("undefined label %q", s)
+	} else {
+		var err error
+		if off, err = strconv.ParseInt(s, 0, 64); err != nil {
+			return 0, fmt.Errorf("invalid jump target %q", s)
+		}
+	}
+	if off < math.MinInt16 || off > math.MaxInt16 {
+		return 0, fmt.Errorf("jump target %q out of range", s)
+	}
+	return int16(off), nil
+}
+
+func makeSlot(op uint8, dst uint8, src uint8, off int16, imm uint32) Slot {
+	return Slot(uint64(op) |
+		uint64(dst&0xF)<<8 |
+		uint64(src&0xF)<<12 |
+		uint64(uint16(off))<<16 |
+		uint64(imm)<<32)
+}
+
+func expectOperands(ops []string, n int) error {
+	if len(ops) != n {
+		return fmt.Errorf("expected %d operands, got %d", n, len(ops))
+	}
+	return nil
+}
+
+// parseReg parses a register operand (r0 to r10).
+func parseReg(s string) (uint8, error) {
+	if !strings.HasPrefix(s, "r") {
+		return 0, fmt.Errorf("invalid register %q", s)
+	}
+	reg, err := strconv.ParseUint(s[1:], 10, 8)
+	if err != nil || reg > 10 {
+		return 0, fmt.Errorf("invalid register %q", s)
+	}
+	return uint8(reg), nil
+}
+
+// parseMem parses a memory operand of the form [rN+off].
+func parseMem(s string) (reg uint8, off int16, err error) {
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return 0, 0, fmt.Errorf("invalid memory operand %q", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	regStr, offStr := s, ""
+	if idx := strings.IndexAny(s, "+-"); idx >= 0 {
+		regStr, offStr = strings.TrimSpace(s[:idx]), strings.ReplaceAll(s[idx:], " ", "")
+	}
+	if reg, err = parseReg(regStr); err != nil {
+		return 0, 0, err
+	}
+	if offStr != "" {
+		x, err := strconv.ParseInt(offStr, 0, 16)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid offset %q", offStr)
+		}
+		off = int16(x)
+	}
+	return reg, off, nil
+}
+
+// parseImm parses a 32-bit immediate.
+//
+// Accepts signed and unsigned 32-bit numbers, and 64-bit numbers
+// that are the sign extension of a 32-bit number.
+func parseImm(s string) (uint32, error) {
+	x, err := parseInt(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if int64(x) < math.MinInt32 || (int64(x) > math.MaxUint32) {
+		return 0, fmt.Errorf("immediate %q out of range", s)
+	}
+	return uint32(x), nil
+}
+
+// parseInt parses a signed or unsigned integer in decimal or hex notation.
+func parseInt(s string, bitSize int) (uint64, error) {
+	if x, err := strconv.ParseInt(s, 0, bitSize); err == nil {
+		return uint64(x), nil
+	}
+	x, err := strconv.ParseUint(strings.TrimPrefix(s, "+"), 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return x, nil
+}
+
+func isAsmIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c == '.' || c == '$':
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
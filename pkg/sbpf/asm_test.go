@@ -0,0 +1,155 @@
+package sbpf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runAsm assembles and runs the given source, returning the values passed to the "result" syscall.
+func runAsm(t *testing.T, src string) ([]uint64, error) {
+	return runAsmVersion(t, VersionV1, src)
+}
+
+// runAsmVersion is like runAsm, but targets the given SBF version.
+func runAsmVersion(t *testing.T, version Version, src string) ([]uint64, error) {
+	program, err := AssembleVersion(src, version)
+	require.NoError(t, err)
+
+	var results []uint64
+	syscalls := NewSyscallRegistry()
+	syscalls.Register("result", SyscallFunc1(func(_ VM, r1 uint64, cuIn int) (uint64, int, error) {
+		results = append(results, r1)
+		return 0, cuIn, nil
+	}))
+	require.NoError(t, program.Verify(syscalls))
+
+	interpreter := NewInterpreter(program, &VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: syscalls,
+	})
+	_, err = interpreter.Run()
+	return results, err
+}
+
+func TestAssemble_Loop(t *testing.T) {
+	results, err := runAsm(t, `
+		mov64 r1, 0
+		mov64 r2, 10
+	loop:
+		add64 r1, r2
+		sub64 r2, 1
+		jne r2, 0, loop
+		call result
+		exit
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{55}, results)
+}
+
+func TestAssemble_Call(t *testing.T) {
+	results, err := runAsm(t, `
+	square:
+		mov64 r0, r1
+		mul64 r0, r1
+		exit
+
+	entrypoint:
+		mov64 r1, 7
+		call square
+		mov64 r1, r0
+		call result
+		lddw r1, 0x1122334455667788
+		stxdw [r10-0x8], r1
+		ldxw r1, [r10-8]
+		call result
+		exit
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{49, 0x55667788}, results)
+}
+
+func TestAssemble_Exception(t *testing.T) {
+	_, err := runAsm(t, `
+		mov64 r1, 0
+		mov64 r2, 1
+		div64 r2, r1
+		exit
+	`)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ExcDivideByZero)
+
+	_, err = runAsm(t, `
+		lddw r1, 0x200000000
+		callx r1
+		exit
+	`)
+	assert.ErrorIs(t, err, ExcCallOutsideText)
+}
+
+func TestAssemble_Roundtrip(t *testing.T) {
+	program, err := Assemble(`
+		lddw r1, 0xffffffffffffffff
+		ldxb r2, [r1+0x10]
+		ldxdw r3, [r10-0x8]
+		stb [r10-0x1], 0x7f
+		stw [r10-0x4], -1
+		stxh [r10-0x2], r2
+		add32 r1, -1
+		or64 r1, 0xffffffff80000000
+		xor64 r1, r2
+		lsh64 r1, 3
+		neg64 r1
+		le16 r1
+		be64 r1
+		ja +1
+		jsgt r1, -0x5, -2
+		jset r1, r2, +0
+		call 0x71e3cf81
+		call r4
+		exit
+	`)
+	require.NoError(t, err)
+
+	var lines []string
+	for pc := 0; pc*SlotSize < len(program.Text); pc++ {
+		slot := GetSlot(program.Text[pc*SlotSize:])
+		var slot2 Slot
+		if IsLongIns(slot.Op()) {
+			slot2 = GetSlot(program.Text[(pc+1)*SlotSize:])
+			pc++
+		}
+		lines = append(lines, Disassemble(slot, slot2))
+	}
+
+	program2, err := Assemble(strings.Join(lines, "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, program.Text, program2.Text)
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+	}{
+		{"Empty", "; nothing here"},
+		{"UnknownMnemonic", "foo r1, r2"},
+		{"BadRegister", "mov64 r11, 1"},
+		{"UndefinedLabel", "ja nowhere\nexit"},
+		{"DuplicateLabel", "a:\na:\nexit"},
+		{"ImmOutOfRange", "mov64 r1, 0x100000000"},
+		{"Operands", "add64 r1"},
+		{"HashComment", "exit # done"},
+		{"LeWithoutSize", "le r1\nexit"},
+		{"BeWithoutSize", "be r1\nexit"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Assemble(c.src)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package sbpf
+
+import "fmt"
+
+// StackFrame is a symbolized call frame.
+type StackFrame struct {
+	PC   int64
+	Func string // name of the function containing PC
+	File string // source file, empty if unknown
+	Line int
+}
+
+func (f StackFrame) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("%s (pc %d)", f.Func, f.PC)
+	}
+	return fmt.Sprintf("%s (pc %d) at %s:%d", f.Func, f.PC, f.File, f.Line)
+}
+
+// Symbolize resolves the function name and source location of an instruction.
+func (p *Program) Symbolize(pc int64) StackFrame {
+	return p.symbols().frame(pc)
+}
+
+// Backtrace symbolizes a call stack given the current PC and the shadow stack.
+//
+// Returns the innermost frame first.
+func (p *Program) Backtrace(pc int64, frames []Frame) []StackFrame {
+	syms := p.symbols()
+	stack := make([]StackFrame, 0, len(frames))
+	stack = append(stack, syms.frame(pc))
+	for i := len(frames) - 1; i >= 1; i-- {
+		stack = append(stack, syms.frame(frames[i].RetAddr-1))
+	}
+	return stack
+}
+
+func (s *symbolizer) frame(pc int64) StackFrame {
+	f := StackFrame{PC: pc, Func: s.funcName(pc)}
+	f.File, f.Line, _ = s.program.Lines.Lookup(pc)
+	return f
+}
@@ -0,0 +1,178 @@
+package sbpf
+
+import "sort"
+
+// CFG is the control flow graph of a program.
+//
+// Nodes are basic blocks, edges are jumps and fall-throughs.
+// Calls do not end a block and are not edges,
+// so the graph of each function is separate.
+type CFG struct {
+	Blocks []CFGBlock // sorted by Start
+	Funcs  []int64    // function entry PCs, sorted
+}
+
+// CFGBlock is a basic block in the control flow graph.
+type CFGBlock struct {
+	Start int64   // PC of the first instruction
+	End   int64   // PC after the last instruction
+	Func  int64   // entry PC of the containing function
+	Succs []int64 // start PCs of successor blocks
+
+	// FallsThrough is set if control flow may continue at End
+	// (i.e. the block does not end with an unconditional jump or exit).
+	FallsThrough bool
+	// Reachable is set if the block is reachable from its function's entry.
+	Reachable bool
+}
+
+// Last returns the PC of the last instruction in the block.
+func (b *CFGBlock) Last(text []byte) int64 {
+	var last int64
+	for pc := b.Start; pc < b.End; pc++ {
+		last = pc
+		if IsLongIns(GetSlot(text[pc*SlotSize:]).Op()) {
+			pc++
+		}
+	}
+	return last
+}
+
+// NewCFG builds the control flow graph of a program.
+//
+// Function entries are the entrypoint, all registered and named functions,
+// and the targets of relative calls (SBFv2).
+// The program must pass the per-instruction checks of the verifier,
+// otherwise this function may panic.
+func NewCFG(p *Program) *CFG {
+	text := p.Text
+	insCount := int64(len(text) / SlotSize)
+
+	funcs := map[int64]struct{}{int64(p.Entrypoint): {}}
+	for _, pc := range p.Funcs {
+		funcs[pc] = struct{}{}
+	}
+	for pc := range p.FuncNames {
+		funcs[pc] = struct{}{}
+	}
+	leaders := map[int64]struct{}{0: {}}
+	forEachIns(text, func(pc int64, ins Slot) {
+		next := pc + 1
+		if IsLongIns(ins.Op()) {
+			next++
+		}
+		switch {
+		case IsJump(ins.Op()):
+			leaders[pc+int64(ins.Off())+1] = struct{}{}
+			leaders[next] = struct{}{}
+		case ins.Op() == OpExit:
+			leaders[next] = struct{}{}
+		case ins.Op() == OpCall && p.Version.StaticSyscalls() && ins.Src() == 1:
+			funcs[pc+int64(ins.Imm())+1] = struct{}{}
+		}
+	})
+
+	cfg := &CFG{Funcs: sortedPCs(funcs, insCount)}
+	for _, pc := range cfg.Funcs {
+		leaders[pc] = struct{}{}
+	}
+	starts := sortedPCs(leaders, insCount)
+
+	cfg.Blocks = make([]CFGBlock, len(starts))
+	fn := 0
+	for i, start := range starts {
+		for fn+1 < len(cfg.Funcs) && cfg.Funcs[fn+1] <= start {
+			fn++
+		}
+		b := &cfg.Blocks[i]
+		b.Start = start
+		b.End = insCount
+		if i+1 < len(starts) {
+			b.End = starts[i+1]
+		}
+		if len(cfg.Funcs) > 0 && cfg.Funcs[fn] <= start {
+			b.Func = cfg.Funcs[fn]
+		}
+
+		ins := GetSlot(text[b.Last(text)*SlotSize:])
+		switch {
+		case ins.Op() == OpJa:
+			b.Succs = []int64{b.Last(text) + int64(ins.Off()) + 1}
+		case IsJump(ins.Op()):
+			b.Succs = []int64{b.End, b.Last(text) + int64(ins.Off()) + 1}
+			b.FallsThrough = true
+		case ins.Op() == OpExit:
+			// no successors
+		default:
+			b.Succs = []int64{b.End}
+			b.FallsThrough = true
+		}
+		if b.End >= insCount {
+			b.Succs = removePC(b.Succs, b.End)
+		}
+	}
+
+	// Mark blocks reachable from function entries
+	var queue []int64
+	for _, pc := range cfg.Funcs {
+		queue = append(queue, pc)
+	}
+	for len(queue) > 0 {
+		b := cfg.Block(queue[len(queue)-1])
+		queue = queue[:len(queue)-1]
+		if b == nil || b.Reachable {
+			continue
+		}
+		b.Reachable = true
+		queue = append(queue, b.Succs...)
+	}
+	return cfg
+}
+
+// Block returns the block starting at pc, or nil if none exists.
+func (c *CFG) Block(pc int64) *CFGBlock {
+	i := sort.Search(len(c.Blocks), func(i int) bool { return c.Blocks[i].Start >= pc })
+	if i >= len(c.Blocks) || c.Blocks[i].Start != pc {
+		return nil
+	}
+	return &c.Blocks[i]
+}
+
+// IsFunc returns whether pc is a function entry.
+func (c *CFG) IsFunc(pc int64) bool {
+	i := sort.Search(len(c.Funcs), func(i int) bool { return c.Funcs[i] >= pc })
+	return i < len(c.Funcs) && c.Funcs[i] == pc
+}
+
+// forEachIns visits every instruction, skipping the second slot of long instructions.
+func forEachIns(text []byte, fn func(pc int64, ins Slot)) {
+	insCount := int64(len(text) / SlotSize)
+	for pc := int64(0); pc < insCount; pc++ {
+		ins := GetSlot(text[pc*SlotSize:])
+		fn(pc, ins)
+		if IsLongIns(ins.Op()) {
+			pc++
+		}
+	}
+}
+
+func sortedPCs(set map[int64]struct{}, insCount int64) []int64 {
+	pcs := make([]int64, 0, len(set))
+	for pc := range set {
+		if pc >= 0 && pc < insCount {
+			pcs = append(pcs, pc)
+		}
+	}
+	sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+	return pcs
+}
+
+func removePC(pcs []int64, pc int64) []int64 {
+	out := pcs[:0]
+	for _, x := range pcs {
+		if x != pc {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package sbpf
+
+// CostTable defines the compute units charged during execution.
+//
+// The interpreter charges instruction costs.
+// Syscalls charge their own costs, looking them up via VM.Costs.
+// A CostTable must not be modified while in use.
+type CostTable struct {
+	// Opcodes holds the cost of an instruction by opcode.
+	Opcodes [256]uint64
+
+	// Syscalls holds the base cost of a syscall by hash.
+	// Syscalls may charge more depending on their inputs.
+	Syscalls map[uint32]uint64
+
+	// MemOpBase is the minimum cost of memory syscalls (memcpy, memmove, memcmp).
+	MemOpBase uint64
+
+	// CPIBytesPerUnit is the number of bytes per compute unit charged
+	// for memory syscalls and data passed in cross-program invocations.
+	CPIBytesPerUnit uint64
+
+	// HashByteCost is the cost per two bytes hashed by hashing syscalls.
+	HashByteCost uint64
+
+	// HashMaxSlices is the maximum number of slices passed to hashing syscalls.
+	HashMaxSlices uint64
+
+	// HeapPageCost is charged before execution for every HeapPageSize bytes of heap after the first.
+	HeapPageCost uint64
+}
+
+// HeapPageSize is the granularity of heap costs.
+const HeapPageSize = 32 * 1024
+
+// NewCostTable creates a cost table charging insCost for every instruction.
+func NewCostTable(insCost uint64) *CostTable {
+	c := &CostTable{Syscalls: make(map[uint32]uint64)}
+	for i := range c.Opcodes {
+		c.Opcodes[i] = insCost
+	}
+	return c
+}
+
+// Clone returns a deep copy of c.
+func (c *CostTable) Clone() *CostTable {
+	clone := *c
+	clone.Syscalls = make(map[uint32]uint64, len(c.Syscalls))
+	for hash, cost := range c.Syscalls {
+		clone.Syscalls[hash] = cost
+	}
+	return &clone
+}
+
+// Syscall returns the base cost of the syscall with the given name.
+func (c *CostTable) Syscall(name string) uint64 {
+	return c.Syscalls[SymbolHash(name)]
+}
+
+// SetSyscall sets the base cost of the syscall with the given name.
+func (c *CostTable) SetSyscall(name string, cost uint64) {
+	c.Syscalls[SymbolHash(name)] = cost
+}
+
+// MemOp returns the cost of a memory syscall accessing n bytes.
+func (c *CostTable) MemOp(n uint64) uint64 {
+	var cost uint64
+	if c.CPIBytesPerUnit != 0 {
+		cost = n / c.CPIBytesPerUnit
+	}
+	if cost < c.MemOpBase {
+		return c.MemOpBase
+	}
+	return cost
+}
+
+// Heap returns the cost of a heap of the given size.
+func (c *CostTable) Heap(size int) uint64 {
+	pages := (uint64(size) + HeapPageSize - 1) / HeapPageSize
+	if pages == 0 {
+		return 0
+	}
+	return (pages - 1) * c.HeapPageCost
+}
+
+// noInsCosts is used if no cost table is configured.
+var noInsCosts [256]uint64
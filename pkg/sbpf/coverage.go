@@ -0,0 +1,175 @@
+package sbpf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Coverage counts how often each instruction of a program was executed.
+//
+// A Coverage may be shared by consecutive runs of the same program.
+// Concurrent runs should record into separate instances and merge them afterwards.
+type Coverage struct {
+	program *Program
+	hits    []uint64 // by PC
+}
+
+// BasicBlock is a sequence of instructions with a single entry and exit.
+type BasicBlock struct {
+	Start int64  // PC of the first instruction
+	End   int64  // PC after the last instruction
+	Hits  uint64 // number of times the block was entered
+}
+
+// NewCoverage creates an empty coverage map for the given program.
+func NewCoverage(p *Program) *Coverage {
+	return &Coverage{
+		program: p,
+		hits:    make([]uint64, len(p.Text)/SlotSize),
+	}
+}
+
+// Hits returns the number of times the instruction at pc was executed.
+func (c *Coverage) Hits(pc int64) uint64 {
+	if pc < 0 || pc >= int64(len(c.hits)) {
+		return 0
+	}
+	return c.hits[pc]
+}
+
+// Merge adds the counts of another coverage map of the same program.
+func (c *Coverage) Merge(other *Coverage) error {
+	if len(other.hits) != len(c.hits) {
+		return fmt.Errorf("cannot merge coverage of different programs")
+	}
+	for i, n := range other.hits {
+		c.hits[i] += n
+	}
+	return nil
+}
+
+// Instructions returns the number of executed and total instructions.
+func (c *Coverage) Instructions() (covered int, total int) {
+	c.forEachIns(func(pc int64, _ Slot) {
+		total++
+		if c.hits[pc] > 0 {
+			covered++
+		}
+	})
+	return
+}
+
+// BasicBlocks returns the basic blocks of the program with execution counts.
+//
+// The blocks are those of the control flow graph (see NewCFG).
+func (c *Coverage) BasicBlocks() []BasicBlock {
+	cfg := NewCFG(c.program)
+	blocks := make([]BasicBlock, len(cfg.Blocks))
+	for i, b := range cfg.Blocks {
+		blocks[i] = BasicBlock{Start: b.Start, End: b.End, Hits: c.hits[b.Start]}
+	}
+	return blocks
+}
+
+// forEachIns visits every instruction, skipping the second slot of long instructions.
+func (c *Coverage) forEachIns(fn func(pc int64, ins Slot)) {
+	forEachIns(c.program.Text, fn)
+}
+
+// WriteLCOV writes an lcov tracefile mapping coverage to source lines.
+//
+// Requires the program to have a line table.
+// The execution count of a line is the highest count of the instructions it maps to.
+func (c *Coverage) WriteLCOV(w io.Writer, testName string) error {
+	lines := c.program.Lines
+	if lines == nil {
+		return fmt.Errorf("program has no line table")
+	}
+
+	type fileCov struct {
+		lines map[int]uint64
+		funcs map[string]int
+		hits  map[string]uint64
+	}
+	files := make(map[string]*fileCov)
+	getFile := func(name string) *fileCov {
+		f, ok := files[name]
+		if !ok {
+			f = &fileCov{
+				lines: make(map[int]uint64),
+				funcs: make(map[string]int),
+				hits:  make(map[string]uint64),
+			}
+			files[name] = f
+		}
+		return f
+	}
+
+	c.forEachIns(func(pc int64, _ Slot) {
+		name, line, ok := lines.Lookup(pc)
+		if !ok {
+			return
+		}
+		f := getFile(name)
+		if n := c.hits[pc]; n >= f.lines[line] {
+			f.lines[line] = n
+		}
+	})
+	syms := c.program.symbols()
+	for _, pc := range syms.starts {
+		name, line, ok := lines.Lookup(pc)
+		if !ok {
+			continue
+		}
+		f := getFile(name)
+		fn := FuncName(c.program, pc)
+		f.funcs[fn] = line
+		f.hits[fn] = c.Hits(pc)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	wr := bufio.NewWriter(w)
+	for _, name := range names {
+		f := files[name]
+		fmt.Fprintf(wr, "TN:%s\nSF:%s\n", testName, name)
+
+		fns := make([]string, 0, len(f.funcs))
+		for fn := range f.funcs {
+			fns = append(fns, fn)
+		}
+		sort.Slice(fns, func(i, j int) bool { return f.funcs[fns[i]] < f.funcs[fns[j]] })
+		var fnHit int
+		for _, fn := range fns {
+			fmt.Fprintf(wr, "FN:%d,%s\n", f.funcs[fn], fn)
+		}
+		for _, fn := range fns {
+			fmt.Fprintf(wr, "FNDA:%d,%s\n", f.hits[fn], fn)
+			if f.hits[fn] > 0 {
+				fnHit++
+			}
+		}
+		fmt.Fprintf(wr, "FNF:%d\nFNH:%d\n", len(fns), fnHit)
+
+		nums := make([]int, 0, len(f.lines))
+		for line := range f.lines {
+			nums = append(nums, line)
+		}
+		sort.Ints(nums)
+		var lineHit int
+		for _, line := range nums {
+			fmt.Fprintf(wr, "DA:%d,%d\n", line, f.lines[line])
+			if f.lines[line] > 0 {
+				lineHit++
+			}
+		}
+		fmt.Fprintf(wr, "LF:%d\nLH:%d\nend_of_record\n", len(nums), lineHit)
+	}
+	return wr.Flush()
+}
@@ -0,0 +1,114 @@
+package sbpf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	program, err := Assemble(`
+		ldxb r2, [r1+0]
+		lddw r0, 0x100000000
+		jeq r2, 0, zero
+		mov64 r0, 1
+		exit
+	zero:
+		mov64 r0, 2
+		exit
+	`)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(NewSyscallRegistry()))
+
+	run := func(input byte) *Coverage {
+		cov := NewCoverage(program)
+		interpreter := NewInterpreter(program, &VMOpts{
+			HeapSize: 32 * 1024,
+			MaxCU:    10000,
+			Syscalls: NewSyscallRegistry(),
+			Input:    []byte{input, 0},
+			Coverage: cov,
+		})
+		_, err := interpreter.Run()
+		require.NoError(t, err)
+		return cov
+	}
+
+	cov := run(0)
+	covered, total := cov.Instructions()
+	assert.Equal(t, 5, covered)
+	assert.Equal(t, 7, total)
+	assert.Equal(t, uint64(1), cov.Hits(6))
+	assert.Equal(t, uint64(0), cov.Hits(4))
+
+	require.NoError(t, cov.Merge(run(1)))
+	require.NoError(t, cov.Merge(run(1)))
+	covered, total = cov.Instructions()
+	assert.Equal(t, 7, covered)
+	assert.Equal(t, 7, total)
+
+	assert.Equal(t, []BasicBlock{
+		{Start: 0, End: 4, Hits: 3},
+		{Start: 4, End: 6, Hits: 2},
+		{Start: 6, End: 8, Hits: 1},
+	}, cov.BasicBlocks())
+
+	var buf bytes.Buffer
+	assert.Error(t, cov.WriteLCOV(&buf, "test"))
+
+	program.Lines = NewLineTable([]LineRow{
+		{PC: 0, File: "main.c", Line: 2},
+		{PC: 1, File: "main.c", Line: 3},
+		{PC: 4, File: "main.c", Line: 4},
+		{PC: 6, File: "main.c", Line: 6},
+		{PC: 8},
+	})
+	program.FuncNames = map[int64]string{0: "main"}
+	require.NoError(t, cov.WriteLCOV(&buf, "test"))
+	assert.Equal(t, `TN:test
+SF:main.c
+FN:2,main
+FNDA:3,main
+FNF:1
+FNH:1
+DA:2,3
+DA:3,3
+DA:4,2
+DA:6,1
+LF:4
+LH:4
+end_of_record
+`, buf.String())
+
+	other, err := Assemble("exit")
+	require.NoError(t, err)
+	assert.Error(t, cov.Merge(NewCoverage(other)))
+}
+
+func TestCoverage_BasicBlocksV2(t *testing.T) {
+	// Relative calls start a function, even if it is entered by falling through
+	program, err := AssembleVersion(`
+	entrypoint:
+		mov64 r1, 1
+		call fn
+	fn:
+		mov64 r0, 2
+		exit
+	`, VersionV2)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(NewSyscallRegistry()))
+
+	cov := NewCoverage(program)
+	_, err = NewInterpreter(program, &VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Coverage: cov,
+	}).Run()
+	require.NoError(t, err)
+	assert.Equal(t, []BasicBlock{
+		{Start: 0, End: 2, Hits: 1},
+		{Start: 2, End: 4, Hits: 2},
+	}, cov.BasicBlocks())
+}
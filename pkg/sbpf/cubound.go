@@ -0,0 +1,200 @@
+package sbpf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// FuncCUBound is the static compute unit bound of a function.
+type FuncCUBound struct {
+	Func int64  // entry PC
+	CU   uint64 // worst-case compute units including callees, valid if Err is nil
+
+	// Err is set if no bound could be determined.
+	// It is a *VerifyError pointing at the offending instruction.
+	Err error
+}
+
+// Bounded returns whether the function has a known upper bound.
+func (b *FuncCUBound) Bounded() bool {
+	return b.Err == nil
+}
+
+// Fits returns whether the function always completes within maxCU compute units.
+func (b *FuncCUBound) Fits(maxCU int) bool {
+	return b.Bounded() && b.CU <= uint64(maxCU)
+}
+
+// CUBounds holds the bounds of all functions, sorted by entry PC.
+type CUBounds []FuncCUBound
+
+// Func returns the bound of the function at the given entry PC.
+func (c CUBounds) Func(pc int64) (FuncCUBound, bool) {
+	i := sort.Search(len(c), func(i int) bool { return c[i].Func >= pc })
+	if i >= len(c) || c[i].Func != pc {
+		return FuncCUBound{}, false
+	}
+	return c[i], true
+}
+
+var (
+	ErrUnboundedLoop  = errors.New("unbounded loop")
+	ErrRecursion      = errors.New("recursive call")
+	ErrIndirectCall   = errors.New("indirect call")
+	ErrUnknownSyscall = errors.New("unknown syscall cost")
+)
+
+// AnalyzeCU computes an upper bound on the compute units used by each function.
+//
+// The analysis walks the control flow graph of each function and takes the most
+// expensive path from the entry to any exit, adding the bounds of called functions.
+// Every loop is considered unbounded, as are recursion and indirect calls (callx).
+// Only the base costs of syscalls are included, not costs depending on their inputs.
+// Calls to syscalls missing from the cost table make the caller unbounded.
+//
+// cfg may be nil, in which case it is built from the program.
+// The program must pass verification.
+func AnalyzeCU(p *Program, cfg *CFG, costs *CostTable) CUBounds {
+	if cfg == nil {
+		cfg = NewCFG(p)
+	}
+	a := cuAnalysis{
+		program: p,
+		cfg:     cfg,
+		costs:   costs,
+		funcs:   make(map[int64]*funcState),
+	}
+	bounds := make(CUBounds, len(cfg.Funcs))
+	for i, pc := range cfg.Funcs {
+		cu, err := a.analyzeFunc(pc)
+		bounds[i] = FuncCUBound{Func: pc, CU: cu, Err: err}
+	}
+	return bounds
+}
+
+type cuAnalysis struct {
+	program *Program
+	cfg     *CFG
+	costs   *CostTable
+	funcs   map[int64]*funcState
+}
+
+type funcState struct {
+	done bool
+	cu   uint64
+	err  error
+}
+
+// Visit states of blocks during depth-first search
+const (
+	blockUnvisited = iota
+	blockActive
+	blockDone
+)
+
+type blockState struct {
+	state uint8
+	cu    uint64 // cost of the most expensive path from the block start
+	err   error
+}
+
+func (a *cuAnalysis) analyzeFunc(entry int64) (uint64, error) {
+	if f, ok := a.funcs[entry]; ok {
+		if !f.done {
+			return 0, ErrRecursion
+		}
+		return f.cu, f.err
+	}
+	f := new(funcState)
+	a.funcs[entry] = f
+	blocks := make(map[int64]*blockState)
+	f.cu, f.err = a.analyzeBlock(entry, blocks)
+	f.done = true
+	return f.cu, f.err
+}
+
+func (a *cuAnalysis) analyzeBlock(start int64, blocks map[int64]*blockState) (uint64, error) {
+	s := blocks[start]
+	if s == nil {
+		s = new(blockState)
+		blocks[start] = s
+	}
+	switch s.state {
+	case blockActive:
+		return 0, ErrUnboundedLoop
+	case blockDone:
+		return s.cu, s.err
+	}
+	s.state = blockActive
+	defer func() { s.state = blockDone }()
+
+	b := a.cfg.Block(start)
+	if b == nil {
+		s.err = fmt.Errorf("no block at pc %d", start)
+		return 0, s.err
+	}
+
+	s.cu, s.err = a.blockCost(b)
+	if s.err != nil {
+		return 0, s.err
+	}
+	var succMax uint64
+	for _, succ := range b.Succs {
+		cu, err := a.analyzeBlock(succ, blocks)
+		if errors.Is(err, ErrUnboundedLoop) && !isVerifyError(err) {
+			err = &VerifyError{PC: b.Last(a.program.Text), Err: err}
+		}
+		if err != nil {
+			s.err = err
+			return 0, err
+		}
+		if cu > succMax {
+			succMax = cu
+		}
+	}
+	s.cu += succMax
+	return s.cu, nil
+}
+
+// blockCost returns the cost of executing a block once, including calls.
+func (a *cuAnalysis) blockCost(b *CFGBlock) (cu uint64, err error) {
+	text := a.program.Text
+	version := a.program.Version
+	for pc := b.Start; pc < b.End; pc++ {
+		ins := GetSlot(text[pc*SlotSize:])
+		cu += a.costs.Opcodes[ins.Op()]
+		switch ins.Op() {
+		case OpLddw:
+			pc++
+		case OpCallx:
+			return 0, &VerifyError{PC: pc, Err: ErrIndirectCall}
+		case OpCall:
+			var callee int64
+			if version.StaticSyscalls() && ins.Src() == 1 {
+				callee = pc + int64(ins.Imm()) + 1
+			} else if cost, ok := a.costs.Syscalls[ins.Uimm()]; ok {
+				cu += cost
+				continue
+			} else if target, ok := a.program.Funcs[ins.Uimm()]; ok && !version.StaticSyscalls() {
+				callee = target
+			} else {
+				return 0, &VerifyError{PC: pc, Err: ErrUnknownSyscall}
+			}
+			calleeCU, err := a.analyzeFunc(callee)
+			if errors.Is(err, ErrRecursion) && !isVerifyError(err) {
+				return 0, &VerifyError{PC: pc, Err: err}
+			}
+			if err != nil {
+				return 0, &VerifyError{PC: pc, Err: fmt.Errorf("call to unbounded function: %w", err)}
+			}
+			cu += calleeCU
+		}
+	}
+	return cu, nil
+}
+
+func isVerifyError(err error) bool {
+	var verr *VerifyError
+	return errors.As(err, &verr)
+}
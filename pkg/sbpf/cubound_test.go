@@ -0,0 +1,86 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analyzeAsm(t *testing.T, src string) CUBounds {
+	program, err := Assemble(src)
+	require.NoError(t, err)
+	// "missing" passes verification but has no cost
+	syscalls := NewSyscallRegistry()
+	for _, name := range []string{"log", "missing"} {
+		syscalls.Register(name, SyscallFunc0(func(_ VM, cuIn int) (uint64, int, error) {
+			return 0, cuIn, nil
+		}))
+	}
+	require.NoError(t, program.Verify(syscalls))
+	costs := NewCostTable(1)
+	costs.SetSyscall("log", 100)
+	return AnalyzeCU(program, nil, costs)
+}
+
+func TestAnalyzeCU(t *testing.T) {
+	bounds := analyzeAsm(t, `
+	entrypoint:
+		jeq r1, 0, short
+		call fn
+		call fn
+		call log
+	short:
+		exit
+	fn:
+		mov64 r0, 1
+		jeq r1, 0, skip
+		mov64 r0, 2
+	skip:
+		exit
+	`)
+	require.Len(t, bounds, 2)
+
+	fn, ok := bounds.Func(5)
+	require.True(t, ok)
+	require.NoError(t, fn.Err)
+	assert.Equal(t, uint64(4), fn.CU)
+
+	entry, ok := bounds.Func(0)
+	require.True(t, ok)
+	require.NoError(t, entry.Err)
+	assert.Equal(t, uint64(5+2*4+100), entry.CU)
+	assert.True(t, entry.Fits(113))
+	assert.False(t, entry.Fits(112))
+
+	_, ok = bounds.Func(1)
+	assert.False(t, ok)
+}
+
+func TestAnalyzeCU_Unbounded(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		err  error
+		pc   int64
+	}{
+		{"Loop", "mov64 r1, 3\nloop:\nsub64 r1, 1\njne r1, 0, loop\nexit", ErrUnboundedLoop, 2},
+		{"Recursion", "entrypoint:\ncall fn\nexit\nfn:\ncall fn\nexit", ErrRecursion, 0},
+		{"Callx", "callx r1\nexit", ErrIndirectCall, 0},
+		{"UnknownSyscall", "call missing\nexit", ErrUnknownSyscall, 0},
+		{"Callee", "entrypoint:\ncall fn\nexit\nfn:\ncallx r1\nexit", ErrIndirectCall, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bounds := analyzeAsm(t, c.src)
+			entry, ok := bounds.Func(0)
+			require.True(t, ok)
+			assert.False(t, entry.Bounded())
+			assert.False(t, entry.Fits(1_400_000))
+			assert.ErrorIs(t, entry.Err, c.err)
+			var verr *VerifyError
+			require.ErrorAs(t, entry.Err, &verr)
+			assert.Equal(t, c.pc, verr.PC)
+		})
+	}
+}
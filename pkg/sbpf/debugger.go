@@ -0,0 +1,241 @@
+package sbpf
+
+import (
+	"errors"
+	"sync"
+)
+
+// Debugger controls the execution of an interpreter instruction by instruction.
+//
+// The program runs in a separate goroutine and stops at breakpoints or after single steps.
+// While stopped, registers and memory may be inspected and modified.
+// All methods must be called from the same controlling goroutine.
+type Debugger struct {
+	program *Program
+	ip      *Interpreter
+
+	mu          sync.Mutex
+	breakpoints map[int64]struct{}
+	stepping    bool
+	killed      bool
+
+	// State of the stopped program, owned by the controlling goroutine while stopped.
+	pc     int64
+	regs   [11]uint64
+	cuLeft int
+
+	started bool
+	exited  bool
+	resume  chan struct{}
+	stops   chan Stop
+}
+
+// StopReason indicates why the program stopped.
+type StopReason int
+
+const (
+	StopStep       = StopReason(iota) // single step completed or program started
+	StopBreakpoint                    // hit a breakpoint
+	StopExit                          // program finished execution
+)
+
+// Stop is reported each time execution stops.
+type Stop struct {
+	Reason StopReason
+	PC     int64
+	Err    error // return value of Run if Reason is StopExit
+}
+
+// ExcKilled is returned when the debugger terminated the program.
+var ExcKilled = errors.New("killed by debugger")
+
+// NewDebugger creates an interpreter for the program under control of a debugger.
+//
+// Execution does not begin until Start is called.
+func NewDebugger(p *Program, opts *VMOpts) *Debugger {
+	d := &Debugger{
+		program:     p,
+		ip:          NewInterpreter(p, opts),
+		breakpoints: make(map[int64]struct{}),
+		stepping:    true,
+		resume:      make(chan struct{}),
+		stops:       make(chan Stop),
+	}
+	d.ip.debug = d
+	return d
+}
+
+// Program returns the program being debugged.
+func (d *Debugger) Program() *Program {
+	return d.program
+}
+
+// Start begins execution and stops before the first instruction.
+func (d *Debugger) Start() Stop {
+	if d.started {
+		panic("debugger already started")
+	}
+	d.started = true
+	go func() {
+		_, err := d.ip.Run()
+		d.pc = d.ip.exitPC
+		var exc *Exception
+		if errors.As(err, &exc) {
+			d.pc = exc.PC
+		}
+		d.stops <- Stop{Reason: StopExit, PC: d.pc, Err: err}
+	}()
+	return d.wait()
+}
+
+// Step executes one instruction.
+func (d *Debugger) Step() Stop {
+	return d.cont(true)
+}
+
+// Continue runs until a breakpoint is hit or the program exits.
+func (d *Debugger) Continue() Stop {
+	return d.cont(false)
+}
+
+// Kill terminates the program.
+func (d *Debugger) Kill() Stop {
+	if d.exited {
+		return Stop{Reason: StopExit, PC: d.pc}
+	}
+	d.mu.Lock()
+	d.killed = true
+	d.mu.Unlock()
+	return d.cont(false)
+}
+
+// Exited returns whether the program finished execution.
+func (d *Debugger) Exited() bool {
+	return d.exited
+}
+
+func (d *Debugger) cont(step bool) Stop {
+	if !d.started {
+		panic("debugger not started")
+	}
+	if d.exited {
+		return Stop{Reason: StopExit, PC: d.pc}
+	}
+	d.mu.Lock()
+	d.stepping = step
+	d.mu.Unlock()
+	d.resume <- struct{}{}
+	return d.wait()
+}
+
+func (d *Debugger) wait() Stop {
+	stop := <-d.stops
+	if stop.Reason == StopExit {
+		d.exited = true
+	}
+	return stop
+}
+
+// SetBreakpoint adds a breakpoint at the given instruction.
+func (d *Debugger) SetBreakpoint(pc int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[pc] = struct{}{}
+}
+
+// ClearBreakpoint removes a breakpoint at the given instruction.
+func (d *Debugger) ClearBreakpoint(pc int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, pc)
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[int64]struct{})
+}
+
+// BreakFunc adds a breakpoint at the start of the function with the given name.
+//
+// Returns false if no such function exists.
+func (d *Debugger) BreakFunc(name string) bool {
+	for pc, funcName := range d.program.FuncNames {
+		if funcName == name {
+			d.SetBreakpoint(pc)
+			return true
+		}
+	}
+	if name == "entrypoint" {
+		d.SetBreakpoint(int64(d.program.Entrypoint))
+		return true
+	}
+	return false
+}
+
+// PC returns the instruction at which the program stopped.
+func (d *Debugger) PC() int64 {
+	return d.pc
+}
+
+// Registers returns the registers of the stopped program.
+func (d *Debugger) Registers() [11]uint64 {
+	return d.regs
+}
+
+// SetRegister modifies a register of the stopped program.
+//
+// Changes to r10 have no effect on the call frame layout.
+func (d *Debugger) SetRegister(reg int, value uint64) {
+	d.regs[reg] = value
+}
+
+// CU returns the compute units left.
+func (d *Debugger) CU() int {
+	return d.cuLeft
+}
+
+// Frames returns a copy of the shadow call stack, the innermost frame last.
+func (d *Debugger) Frames() []Frame {
+	return append([]Frame(nil), d.ip.stack.shadow...)
+}
+
+// Backtrace returns the symbolized call stack of the stopped program, the innermost frame first.
+func (d *Debugger) Backtrace() []StackFrame {
+	return d.program.Backtrace(d.pc, d.ip.stack.shadow)
+}
+
+// VM returns the memory interface of the stopped program.
+func (d *Debugger) VM() VM {
+	return d.ip
+}
+
+// hook is invoked by the interpreter before executing each instruction.
+//
+// Blocks the interpreter while the program is stopped.
+func (d *Debugger) hook(pc int64, regs [11]uint64, cuLeft int) ([11]uint64, error) {
+	d.mu.Lock()
+	_, isBreakpoint := d.breakpoints[pc]
+	stepping := d.stepping
+	d.mu.Unlock()
+	if !isBreakpoint && !stepping {
+		return regs, nil
+	}
+
+	d.pc, d.regs, d.cuLeft = pc, regs, cuLeft
+	reason := StopStep
+	if isBreakpoint {
+		reason = StopBreakpoint
+	}
+	d.stops <- Stop{Reason: reason, PC: pc}
+	<-d.resume
+
+	d.mu.Lock()
+	killed := d.killed
+	d.mu.Unlock()
+	if killed {
+		return d.regs, ExcKilled
+	}
+	return d.regs, nil
+}
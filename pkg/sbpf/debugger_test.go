@@ -0,0 +1,101 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const debuggerTestAsm = `
+square:
+	mov64 r0, r1
+	mul64 r0, r1
+	exit
+
+entrypoint:
+	mov64 r1, 3
+	call square
+	stxdw [r10-0x8], r0
+	mov64 r1, r0
+	exit
+`
+
+func newTestDebugger(t *testing.T) *Debugger {
+	program, err := Assemble(debuggerTestAsm)
+	require.NoError(t, err)
+	program.FuncNames = map[int64]string{0: "square"}
+	return NewDebugger(program, &VMOpts{
+		HeapSize: 1024,
+		MaxCU:    100,
+	})
+}
+
+func TestDebugger_Step(t *testing.T) {
+	d := newTestDebugger(t)
+
+	stop := d.Start()
+	assert.Equal(t, Stop{Reason: StopStep, PC: 3}, stop)
+	assert.Equal(t, 100, d.CU())
+
+	stop = d.Step()
+	assert.Equal(t, Stop{Reason: StopStep, PC: 4}, stop)
+	assert.Equal(t, uint64(3), d.Registers()[1])
+
+	// Step into call
+	stop = d.Step()
+	assert.Equal(t, Stop{Reason: StopStep, PC: 0}, stop)
+	require.Len(t, d.Frames(), 2)
+	assert.Equal(t, int64(5), d.Frames()[1].RetAddr)
+
+	// Modify argument
+	d.SetRegister(1, 5)
+	d.Step()
+	d.Step()
+	stop = d.Step()
+	assert.Equal(t, Stop{Reason: StopStep, PC: 5}, stop)
+	assert.Equal(t, uint64(25), d.Registers()[0])
+
+	// Inspect memory written by the program
+	d.Step()
+	value, err := d.VM().Read64(d.Registers()[10] - 8)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(25), value)
+
+	// Exit reports the exit instruction, which was not stepped over
+	stop = d.Continue()
+	assert.Equal(t, Stop{Reason: StopExit, PC: 7}, stop)
+	assert.Equal(t, int64(7), d.PC())
+	assert.True(t, d.Exited())
+}
+
+func TestDebugger_Breakpoints(t *testing.T) {
+	d := newTestDebugger(t)
+	d.Start()
+
+	require.True(t, d.BreakFunc("square"))
+	assert.False(t, d.BreakFunc("nonexistent"))
+	d.SetBreakpoint(6)
+
+	stop := d.Continue()
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 0}, stop)
+	assert.Equal(t, uint64(3), d.Registers()[1])
+
+	stop = d.Continue()
+	assert.Equal(t, Stop{Reason: StopBreakpoint, PC: 6}, stop)
+	assert.Equal(t, uint64(9), d.Registers()[0])
+
+	d.ClearBreakpoint(6)
+	stop = d.Continue()
+	assert.Equal(t, Stop{Reason: StopExit, PC: 7}, stop)
+}
+
+func TestDebugger_Kill(t *testing.T) {
+	d := newTestDebugger(t)
+	d.Start()
+
+	stop := d.Kill()
+	assert.Equal(t, StopExit, stop.Reason)
+	assert.ErrorIs(t, stop.Err, ExcKilled)
+	assert.Equal(t, int64(3), stop.PC)
+}
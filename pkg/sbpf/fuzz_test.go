@@ -0,0 +1,102 @@
+package sbpf_test
+
+import (
+	"debug/elf"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sealevel"
+)
+
+// addFuzzSeeds adds the text sections of all fixture programs and some assembled programs.
+func addFuzzSeeds(f *testing.F) {
+	files, err := filepath.Glob(fixtures.Path(f, "sbpf", "*.so"))
+	require.NoError(f, err)
+	for _, path := range files {
+		file, err := elf.Open(path)
+		if err != nil {
+			continue
+		}
+		if text := file.Section(".text"); text != nil {
+			if data, err := text.Data(); err == nil {
+				f.Add(data, uint8(sbpf.VersionV1))
+				f.Add(data, uint8(sbpf.VersionV2))
+			}
+		}
+		file.Close()
+	}
+	for _, src := range []string{
+		"mov64 r0, 1\nexit",
+		"lddw r1, 0x100000000\nldxdw r0, [r1+0]\nexit",
+		"mov64 r1, 0\ndiv64 r0, r1\nexit",
+		"entrypoint:\nmov64 r1, 3\ncall fn\nsub64 r1, 1\njne r1, 0, -3\nexit\nfn:\nstxdw [r10-8], r1\nexit",
+		"lddw r1, 0x100000010\ncallx r1\nexit",
+		"ja -1",
+	} {
+		program, err := sbpf.Assemble(src)
+		require.NoError(f, err)
+		f.Add(program.Text, uint8(sbpf.VersionV1))
+	}
+}
+
+// fuzzSyscalls are the Sealevel syscalls, such that fuzzed programs reach syscall code.
+var fuzzSyscalls = sealevel.Syscalls()
+
+// fuzzRun executes a program if it passes verification.
+//
+// Verified programs must not panic and may only fail with an *sbpf.Exception.
+func fuzzRun(t *testing.T, program *sbpf.Program) {
+	if err := program.Verify(fuzzSyscalls); err != nil {
+		return
+	}
+	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: 32 * 1024,
+		Syscalls: fuzzSyscalls,
+		Costs:    sealevel.DefaultCosts(),
+		MaxCU:    10_000,
+		Context:  &sealevel.Execution{Log: new(sealevel.LogCollector)},
+		Input:    make([]byte, 64),
+	})
+	if _, err := interpreter.Run(); err != nil {
+		if _, ok := err.(*sbpf.Exception); !ok {
+			t.Fatalf("unexpected error type %T: %s", err, err)
+		}
+	}
+}
+
+func fuzzProgram(text []byte, version uint8) *sbpf.Program {
+	return &sbpf.Program{
+		Version: sbpf.Version(version % 2),
+		RO:      text,
+		Text:    text,
+		TextVA:  sbpf.VaddrProgram,
+	}
+}
+
+func FuzzVerify(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, text []byte, version uint8) {
+		program := fuzzProgram(text, version)
+		verifier := sbpf.NewVerifier(program)
+		verifier.Syscalls = fuzzSyscalls
+		if err := verifier.Verify(); err != nil {
+			var verr *sbpf.VerifyError
+			if errors.As(err, &verr) && (verr.PC < 0 || verr.PC >= int64(len(text)/sbpf.SlotSize)) {
+				t.Fatalf("error PC out of bounds: %s", err)
+			}
+			return
+		}
+		sbpf.AnalyzeCU(program, verifier.CFG, sbpf.NewCostTable(1))
+	})
+}
+
+func FuzzInterpreter(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, text []byte, version uint8) {
+		fuzzRun(t, fuzzProgram(text, version))
+	})
+}
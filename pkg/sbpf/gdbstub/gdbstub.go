@@ -0,0 +1,458 @@
+// Package gdbstub implements the GDB remote serial protocol for the SBF debugger.
+//
+// The stub exposes registers r0-r10 followed by the program counter as 64-bit little-endian values.
+// Code addresses are virtual addresses (sbpf.Program.TextVA + 8*PC).
+// Attach with a BPF-capable GDB using `target remote <addr>`.
+//
+// Reference: https://sourceware.org/gdb/current/onlinedocs/gdb.html/Remote-Protocol.html
+package gdbstub
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"k8s.io/klog/v2"
+)
+
+// regCount is the number of registers exposed to GDB (r0-r10, pc).
+const regCount = 12
+
+const targetXML = `<?xml version="1.0"?>
+<!DOCTYPE target SYSTEM "gdb-target.dtd">
+<target version="1.0">
+  <architecture>bpf</architecture>
+</target>`
+
+// ListenAndServe waits for one GDB connection on the given TCP address and serves the debug session.
+func ListenAndServe(addr string, d *sbpf.Debugger) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	klog.Infof("Waiting for GDB on %s", ln.Addr())
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	klog.Infof("GDB attached from %s", conn.RemoteAddr())
+	return Serve(conn, d)
+}
+
+// Serve starts the debugger and runs a debug session over the given connection
+// until the client detaches or kills the program.
+//
+// The program has finished when Serve returns: it runs to completion if the
+// client detached, and is killed if the session ended otherwise.
+func Serve(conn io.ReadWriter, d *sbpf.Debugger) error {
+	s := &session{
+		rd:  bufio.NewReader(conn),
+		wr:  conn,
+		d:   d,
+		ack: true,
+	}
+	s.last = d.Start()
+	return s.serve()
+}
+
+type session struct {
+	rd   *bufio.Reader
+	wr   io.Writer
+	d    *sbpf.Debugger
+	ack  bool
+	last sbpf.Stop
+
+	detached bool
+}
+
+var errDone = errors.New("session done")
+
+func (s *session) serve() error {
+	err := s.serveCommands()
+	if !s.d.Exited() {
+		if s.detached {
+			s.last = s.d.Continue()
+		} else {
+			s.last = s.d.Kill()
+		}
+	}
+	return err
+}
+
+func (s *session) serveCommands() error {
+	for {
+		pkt, err := s.readPacket()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		reply, err := s.handle(pkt)
+		if err != nil && !errors.Is(err, errDone) {
+			return err
+		}
+		if reply != nil {
+			if werr := s.writePacket(*reply); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, errDone) {
+			return nil
+		}
+	}
+}
+
+// readPacket reads the next command packet, acknowledging it if required.
+func (s *session) readPacket() (string, error) {
+	for {
+		b, err := s.rd.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '$':
+			// packet follows
+		case 0x03:
+			// Interrupts are not supported, the program is always stopped while GDB is in control.
+			continue
+		default:
+			continue // acks, noise
+		}
+
+		data, err := s.rd.ReadString('#')
+		if err != nil {
+			return "", err
+		}
+		data = data[:len(data)-1]
+		var sum [2]byte
+		if _, err := io.ReadFull(s.rd, sum[:]); err != nil {
+			return "", err
+		}
+		want, err := strconv.ParseUint(string(sum[:]), 16, 8)
+		if err != nil || uint8(want) != checksum(data) {
+			if s.ack {
+				if _, err := s.wr.Write([]byte{'-'}); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if s.ack {
+			if _, err := s.wr.Write([]byte{'+'}); err != nil {
+				return "", err
+			}
+		}
+		return data, nil
+	}
+}
+
+// writePacket sends a reply packet.
+func (s *session) writePacket(data string) error {
+	_, err := fmt.Fprintf(s.wr, "$%s#%02x", escape(data), checksum(escape(data)))
+	return err
+}
+
+func checksum(data string) uint8 {
+	var sum uint8
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
+
+func escape(data string) string {
+	if !strings.ContainsAny(data, "#$}*") {
+		return data
+	}
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '#', '$', '}', '*':
+			b.WriteByte('}')
+			b.WriteByte(c ^ 0x20)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func reply(s string) *string {
+	return &s
+}
+
+// Common replies
+var (
+	replyOK      = reply("OK")
+	replyEmpty   = reply("")
+	replyInvalid = reply("E01")
+	replyMemory  = reply("E14")
+)
+
+// handle executes a command packet and returns the reply.
+func (s *session) handle(pkt string) (*string, error) {
+	if pkt == "" {
+		return replyEmpty, nil
+	}
+	cmd, args := pkt[0], pkt[1:]
+	switch cmd {
+	case '?':
+		return reply(s.stopReply(s.last)), nil
+	case 'g':
+		return reply(s.readRegisters()), nil
+	case 'G':
+		return s.writeRegisters(args), nil
+	case 'p':
+		return s.readRegister(args), nil
+	case 'P':
+		return s.writeRegister(args), nil
+	case 'm':
+		return s.readMemory(args), nil
+	case 'M':
+		return s.writeMemory(args), nil
+	case 'c':
+		s.last = s.d.Continue()
+		return reply(s.stopReply(s.last)), nil
+	case 's':
+		s.last = s.d.Step()
+		return reply(s.stopReply(s.last)), nil
+	case 'Z', 'z':
+		return s.breakpoint(cmd == 'Z', args), nil
+	case 'k':
+		s.d.Kill()
+		return nil, errDone
+	case 'D':
+		s.d.ClearBreakpoints()
+		s.detached = true
+		return replyOK, errDone
+	case 'H', 'T':
+		return replyOK, nil
+	case 'q':
+		return s.query(args), nil
+	case 'Q':
+		if args == "StartNoAckMode" {
+			_, err := s.wr.Write([]byte("$OK#9a"))
+			s.ack = false
+			return nil, err
+		}
+		return replyEmpty, nil
+	default:
+		return replyEmpty, nil
+	}
+}
+
+func (s *session) query(args string) *string {
+	switch {
+	case strings.HasPrefix(args, "Supported"):
+		return reply("PacketSize=4000;qXfer:features:read+;QStartNoAckMode+")
+	case args == "Attached":
+		return reply("1")
+	case args == "C":
+		return reply("QC1")
+	case args == "fThreadInfo":
+		return reply("m1")
+	case args == "sThreadInfo":
+		return reply("l")
+	case strings.HasPrefix(args, "Xfer:features:read:target.xml:"):
+		return xferRead(targetXML, strings.TrimPrefix(args, "Xfer:features:read:target.xml:"))
+	default:
+		return replyEmpty
+	}
+}
+
+// xferRead serves a chunk of a qXfer object.
+func xferRead(doc string, args string) *string {
+	offStr, lenStr, ok := strings.Cut(args, ",")
+	if !ok {
+		return replyInvalid
+	}
+	off, err1 := strconv.ParseUint(offStr, 16, 32)
+	n, err2 := strconv.ParseUint(lenStr, 16, 32)
+	if err1 != nil || err2 != nil {
+		return replyInvalid
+	}
+	if off >= uint64(len(doc)) {
+		return reply("l")
+	}
+	chunk := doc[off:]
+	if uint64(len(chunk)) > n {
+		return reply("m" + chunk[:n])
+	}
+	return reply("l" + chunk)
+}
+
+// stopReply formats a stop event.
+func (s *session) stopReply(stop sbpf.Stop) string {
+	if stop.Reason != sbpf.StopExit {
+		return "S05" // SIGTRAP
+	}
+	if stop.Err != nil {
+		klog.Infof("Program failed: %s", stop.Err)
+		return "X06" // SIGABRT
+	}
+	return "W00"
+}
+
+// pcAddr converts a program counter to a virtual address.
+func (s *session) pcAddr(pc int64) uint64 {
+	return s.d.Program().TextVA + uint64(pc)*sbpf.SlotSize
+}
+
+// addrPC converts a virtual address to a program counter.
+func (s *session) addrPC(addr uint64) (int64, bool) {
+	textVA := s.d.Program().TextVA
+	if addr < textVA || (addr-textVA)%sbpf.SlotSize != 0 {
+		return 0, false
+	}
+	return int64((addr - textVA) / sbpf.SlotSize), true
+}
+
+func (s *session) getRegister(i int) uint64 {
+	if i == regCount-1 {
+		return s.pcAddr(s.d.PC())
+	}
+	return s.d.Registers()[i]
+}
+
+func encodeReg(v uint64) string {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return hex.EncodeToString(buf[:])
+}
+
+func decodeReg(s string) (uint64, bool) {
+	buf, err := hex.DecodeString(s)
+	if err != nil || len(buf) != 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(buf), true
+}
+
+func (s *session) readRegisters() string {
+	var b strings.Builder
+	for i := 0; i < regCount; i++ {
+		b.WriteString(encodeReg(s.getRegister(i)))
+	}
+	return b.String()
+}
+
+func (s *session) writeRegisters(args string) *string {
+	if len(args) != regCount*16 {
+		return replyInvalid
+	}
+	for i := 0; i < regCount-1; i++ {
+		v, ok := decodeReg(args[i*16 : (i+1)*16])
+		if !ok {
+			return replyInvalid
+		}
+		s.d.SetRegister(i, v)
+	}
+	return replyOK
+}
+
+func (s *session) readRegister(args string) *string {
+	i, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || i >= regCount {
+		return replyInvalid
+	}
+	return reply(encodeReg(s.getRegister(int(i))))
+}
+
+func (s *session) writeRegister(args string) *string {
+	idxStr, valStr, ok := strings.Cut(args, "=")
+	if !ok {
+		return replyInvalid
+	}
+	i, err := strconv.ParseUint(idxStr, 16, 8)
+	if err != nil || i >= regCount-1 {
+		return replyInvalid // pc is read-only
+	}
+	v, ok := decodeReg(valStr)
+	if !ok {
+		return replyInvalid
+	}
+	s.d.SetRegister(int(i), v)
+	return replyOK
+}
+
+// parseAddrLen parses an "addr,length" argument.
+func parseAddrLen(args string) (addr uint64, n uint32, ok bool) {
+	addrStr, lenStr, ok := strings.Cut(args, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	addr, err := strconv.ParseUint(addrStr, 16, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	n64, err := strconv.ParseUint(lenStr, 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return addr, uint32(n64), true
+}
+
+func (s *session) readMemory(args string) *string {
+	addr, n, ok := parseAddrLen(args)
+	if !ok || n > 0x1000 {
+		return replyInvalid
+	}
+	buf := make([]byte, n)
+	if err := s.d.VM().Read(addr, buf); err != nil {
+		return replyMemory
+	}
+	return reply(hex.EncodeToString(buf))
+}
+
+func (s *session) writeMemory(args string) *string {
+	loc, data, ok := strings.Cut(args, ":")
+	if !ok {
+		return replyInvalid
+	}
+	addr, n, ok := parseAddrLen(loc)
+	if !ok {
+		return replyInvalid
+	}
+	buf, err := hex.DecodeString(data)
+	if err != nil || uint32(len(buf)) != n {
+		return replyInvalid
+	}
+	if err := s.d.VM().Write(addr, buf); err != nil {
+		return replyMemory
+	}
+	return replyOK
+}
+
+func (s *session) breakpoint(insert bool, args string) *string {
+	parts := strings.Split(args, ",")
+	if len(parts) < 2 {
+		return replyInvalid
+	}
+	if parts[0] != "0" && parts[0] != "1" {
+		return replyEmpty // watchpoints not supported
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 64)
+	if err != nil {
+		return replyInvalid
+	}
+	pc, ok := s.addrPC(addr)
+	if !ok {
+		return replyInvalid
+	}
+	if insert {
+		s.d.SetBreakpoint(pc)
+	} else {
+		s.d.ClearBreakpoint(pc)
+	}
+	return replyOK
+}
@@ -0,0 +1,129 @@
+package gdbstub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// call sends a command and returns the reply.
+func (c *testClient) call(cmd string) string {
+	_, err := fmt.Fprintf(c.conn, "$%s#%02x", cmd, checksum(cmd))
+	require.NoError(c.t, err)
+
+	ack, err := c.rd.ReadByte()
+	require.NoError(c.t, err)
+	require.Equal(c.t, byte('+'), ack)
+
+	if cmd == "k" {
+		return ""
+	}
+
+	start, err := c.rd.ReadByte()
+	require.NoError(c.t, err)
+	require.Equal(c.t, byte('$'), start)
+	data, err := c.rd.ReadString('#')
+	require.NoError(c.t, err)
+	var sum [2]byte
+	_, err = io.ReadFull(c.rd, sum[:])
+	require.NoError(c.t, err)
+
+	data = data[:len(data)-1]
+	assert.Equal(c.t, fmt.Sprintf("%02x", checksum(data)), string(sum[:]))
+	if cmd == "D" {
+		return data // the session is over, nobody reads the ack
+	}
+	_, err = c.conn.Write([]byte{'+'})
+	require.NoError(c.t, err)
+	return data
+}
+
+const testAsm = `
+	mov64 r1, 1
+	mov64 r2, 2
+	add64 r1, r2
+	stxdw [r10-0x8], r1
+	exit
+`
+
+// serveTest starts a debug session of testAsm over an in-memory connection.
+// Serve returns its result on the returned channel.
+func serveTest(t *testing.T) (*sbpf.Debugger, *testClient, <-chan error) {
+	program, err := sbpf.Assemble(testAsm)
+	require.NoError(t, err)
+	d := sbpf.NewDebugger(program, &sbpf.VMOpts{HeapSize: 1024, MaxCU: 100})
+
+	server, client := net.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(server, d)
+	}()
+	return d, &testClient{t: t, conn: client, rd: bufio.NewReader(client)}, done
+}
+
+func TestServe(t *testing.T) {
+	_, c, done := serveTest(t)
+
+	assert.Contains(t, c.call("qSupported:multiprocess+"), "PacketSize=")
+	assert.Equal(t, "S05", c.call("?"))
+	assert.Equal(t, "0000000001000000", c.call("pb")) // pc = TextVA
+	assert.Equal(t, "l"+targetXML, c.call("qXfer:features:read:target.xml:0,1000"))
+
+	// Break on the store instruction
+	assert.Equal(t, "OK", c.call("Z0,100000018,8"))
+	assert.Equal(t, "S05", c.call("c"))
+	assert.Equal(t, "1800000001000000", c.call("pb"))
+	assert.Equal(t, "0300000000000000", c.call("p1"))
+
+	// Change r1 before the store and step over it
+	assert.Equal(t, "OK", c.call("P1=2a00000000000000"))
+	assert.Equal(t, "S05", c.call("s"))
+	regs := c.call("g")
+	require.Len(t, regs, regCount*16)
+	assert.Equal(t, "2000000001000000", regs[11*16:])
+
+	// Read back stored value from the stack
+	fp, ok := decodeReg(regs[10*16 : 11*16])
+	require.True(t, ok)
+	assert.Equal(t, "2a00000000000000", c.call(fmt.Sprintf("m%x,8", fp-8)))
+	assert.Equal(t, "E14", c.call("m0,8"))
+
+	assert.Equal(t, "OK", c.call("z0,100000018,8"))
+	assert.Equal(t, "W00", c.call("c"))
+	c.call("k")
+
+	require.NoError(t, <-done)
+}
+
+func TestServe_Detach(t *testing.T) {
+	d, c, done := serveTest(t)
+	assert.Equal(t, "OK", c.call("Z0,100000018,8"))
+	assert.Equal(t, "S05", c.call("c"))
+
+	// Runs to completion without breakpoints
+	assert.Equal(t, "OK", c.call("D"))
+	require.NoError(t, <-done)
+	assert.True(t, d.Exited())
+}
+
+func TestServe_Disconnect(t *testing.T) {
+	d, c, done := serveTest(t)
+	assert.Equal(t, "S05", c.call("?"))
+
+	// The program is killed when the client goes away
+	require.NoError(t, c.conn.Close())
+	require.NoError(t, <-done)
+	assert.True(t, d.Exited())
+}
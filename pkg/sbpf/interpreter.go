@@ -1,65 +1,187 @@
 package sbpf
 
 import (
-	"fmt"
+	"context"
 	"math"
 	"math/bits"
+	"sync"
+	"time"
 	"unsafe"
 )
 
 // Interpreter implements the SBF core in pure Go.
 type Interpreter struct {
-	textVA uint64
-	text   []byte
-	ro     []byte
-	stack  Stack
-	heap   []byte
-	input  []byte
+	program *Program
+	version Version
 
-	entry uint64
-	cuMax int
+	textVA  uint64
+	text    []byte
+	stack   Stack
+	heap    []byte
+	regions []MemoryRegion // scratch buffer for building the memory mapping
+	mem     *MemoryMapping
+	memErr  error
+
+	entry    uint64
+	cuMax    int
+	cuHeap   int // charged before the first instruction
+	deadline time.Time
+	costs    *CostTable
+	insCosts *[256]uint64
 
 	syscalls  map[uint32]Syscall
 	funcs     map[uint32]int64
 	vmContext any
-	trace     TraceSink
-}
-
-type TraceSink interface {
-	Printf(format string, v ...any)
+	trace     Tracer
+	prof      *Profiler
+	cov       *Coverage
+	traceEv   TraceEvent
+	debug     *Debugger
+	exitPC    int64 // exit instruction that ended the program
 }
 
 // NewInterpreter creates a new interpreter instance for a program execution.
 //
-// The caller must create a new interpreter object for every new execution.
-// In other words, Run may only be called once per interpreter.
+// Run may only be called once per execution.
+// Call Reset to reuse the interpreter for another execution.
 func NewInterpreter(p *Program, opts *VMOpts) *Interpreter {
-	return &Interpreter{
+	ip := new(Interpreter)
+	ip.Reset(p, opts)
+	return ip
+}
+
+// Reset prepares the interpreter for a new program execution.
+//
+// The stack, heap and memory mapping of the previous execution are zeroed
+// and reused, such that resetting does not allocate unless the new
+// execution requires a larger heap.
+// Memory returned by Translate during previous executions must no longer be used.
+func (ip *Interpreter) Reset(p *Program, opts *VMOpts) {
+	*ip = Interpreter{
+		program:   p,
+		version:   p.Version,
 		textVA:    p.TextVA,
 		text:      p.Text,
-		ro:        p.RO,
-		stack:     NewStack(),
-		heap:      make([]byte, opts.HeapSize),
-		input:     opts.Input,
+		stack:     ip.stack,
+		mem:       ip.mem,
 		entry:     p.Entrypoint,
 		cuMax:     opts.MaxCU,
-		syscalls:  opts.Syscalls,
+		deadline:  opts.Deadline,
+		costs:     opts.Costs,
+		insCosts:  &noInsCosts,
+		syscalls:  opts.Syscalls.syscalls,
 		funcs:     p.Funcs,
 		vmContext: opts.Context,
 		trace:     opts.Tracer,
+		prof:      opts.Profiler,
+		cov:       opts.Coverage,
+		traceEv:   TraceEvent{Mem: ip.traceEv.Mem[:0]},
+		heap:      ip.heap,
+		regions:   ip.regions[:0],
+	}
+
+	if opts.Costs != nil {
+		ip.insCosts = &opts.Costs.Opcodes
+		ip.cuHeap = int(opts.Costs.Heap(opts.HeapSize))
+	}
+	if ip.stack.mem == nil {
+		ip.stack = newStack(p.Version)
+	} else {
+		zero(ip.stack.mem)
+		ip.stack.reset(p.Version.DynamicStackFrames())
+	}
+	if cap(ip.heap) < opts.HeapSize {
+		ip.heap = make([]byte, opts.HeapSize)
+	} else {
+		ip.heap = ip.heap[:opts.HeapSize]
+		zero(ip.heap)
 	}
+	if ip.mem == nil {
+		ip.mem = new(MemoryMapping)
+	}
+	ip.memErr = ip.resetMemoryMapping(opts)
+}
+
+// resetMemoryMapping maps the program, stack, heap, and input segments.
+func (ip *Interpreter) resetMemoryMapping(opts *VMOpts) error {
+	var stackGap uint64
+	if !ip.stack.dynamic {
+		stackGap = StackFrameSize
+	}
+	ip.regions = append(ip.regions,
+		MemoryRegion{Vaddr: VaddrProgram, Data: ip.program.RO},
+		MemoryRegion{Vaddr: VaddrStack, Data: ip.stack.mem, Writable: true, GapSize: stackGap},
+		MemoryRegion{Vaddr: VaddrHeap, Data: ip.heap, Writable: true},
+	)
+	if opts.InputRegions != nil {
+		ip.regions = append(ip.regions, opts.InputRegions...)
+	} else {
+		ip.regions = append(ip.regions, MemoryRegion{Vaddr: VaddrInput, Data: opts.Input, Writable: true})
+	}
+	return ip.mem.reset(opts.Mapping, ip.regions)
+}
+
+var interpreterPool = sync.Pool{
+	New: func() any { return new(Interpreter) },
+}
+
+// GetInterpreter returns an interpreter from a shared pool, reset for the given execution.
+//
+// Pass it to PutInterpreter when done to reuse its memory.
+func GetInterpreter(p *Program, opts *VMOpts) *Interpreter {
+	ip := interpreterPool.Get().(*Interpreter)
+	ip.Reset(p, opts)
+	return ip
+}
+
+// PutInterpreter returns an interpreter to the shared pool.
+//
+// Neither the interpreter nor memory returned by it may be used afterwards.
+func PutInterpreter(ip *Interpreter) {
+	// Drop references to the execution, keep buffers
+	*ip = Interpreter{
+		stack:   ip.stack,
+		mem:     ip.mem,
+		traceEv: TraceEvent{Mem: ip.traceEv.Mem[:0]},
+		heap:    ip.heap,
+		regions: ip.regions[:0],
+	}
+	interpreterPool.Put(ip)
 }
 
 // Run executes the program.
 //
+// The result is also returned if the program raised an exception,
+// with the state at the faulting instruction.
+//
 // This function may panic given code that doesn't pass the static verifier.
-func (ip *Interpreter) Run() (err error) {
+func (ip *Interpreter) Run() (res Result, err error) {
+	return ip.RunContext(context.Background())
+}
+
+// cancelCheckInterval is the number of instructions between checks
+// for cancellation and the wall-clock deadline. Must be a power of two.
+const cancelCheckInterval = 1024
+
+// RunContext is like Run, but stops with ExcCancelled once ctx is done.
+//
+// Cancellation is checked every few instructions, so syscalls and
+// short executions run to completion.
+func (ip *Interpreter) RunContext(ctx context.Context) (res Result, err error) {
 	var r [11]uint64
 	r[1] = VaddrInput
 	r[10] = ip.stack.GetFramePtr()
 	// TODO frame pointer
 	pc := int64(ip.entry)
-	cuLeft := int(ip.cuMax)
+	if ip.memErr != nil {
+		return ip.result(0, ip.cuMax, 0), ip.exception(pc, ip.memErr)
+	}
+	cuLeft := ip.cuMax - ip.cuHeap
+	if cuLeft < 0 {
+		return ip.result(r[0], cuLeft, 0), ip.exception(pc, ExcOutOfCU)
+	}
+	done := ctx.Done()
+	interruptible := done != nil || !ip.deadline.IsZero()
 
 	// Design notes
 	// - The interpreter is deliberately implemented in a single big loop,
@@ -68,14 +190,36 @@ func (ip *Interpreter) Run() (err error) {
 	// - The static verifier imposes invariants on the bytecode.
 	//   The interpreter may panic when it notices these invariants are violated (e.g. invalid opcode)
 
-mainLoop:
-	for i := 0; true; i++ {
+	for i := uint64(0); ; i++ {
+		if ip.debug != nil {
+			if r, err = ip.debug.hook(pc, r, cuLeft); err != nil {
+				return ip.result(r[0], cuLeft, i), ip.exception(pc, err)
+			}
+		}
+		if interruptible && i%cancelCheckInterval == 0 {
+			if err = ip.interrupted(done); err != nil {
+				return ip.result(r[0], cuLeft, i), ip.exception(pc, err)
+			}
+		}
 		// Fetch
+		if uint64(pc) >= uint64(len(ip.text)/SlotSize) {
+			return ip.result(r[0], cuLeft, i), ip.exception(pc, ExcExecutionOverrun)
+		}
 		ins := ip.getSlot(pc)
+		cost := int(ip.insCosts[ins.Op()])
+		if cuLeft < cost {
+			return ip.result(r[0], cuLeft-cost, i), ip.exception(pc, ExcOutOfCU)
+		}
 		if ip.trace != nil {
-			ip.trace.Printf("% 5d [%016x, %016x, %016x, %016x, %016x, %016x, %016x, %016x, %016x, %016x, %016x] % 5d: %s",
-				i, r[0], r[1], r[2], r[3], r[4], r[5], r[6], r[7], r[8], r[9], r[10], pc+29 /*todo weird offset*/, disassemble(ins /*todo*/, 0))
+			ip.traceBefore(i, pc, ins, &r, cuLeft)
+		}
+		if ip.prof != nil {
+			ip.prof.begin(pc, ip.stack.shadow, cuLeft)
+		}
+		if ip.cov != nil {
+			ip.cov.hits[pc]++
 		}
+		cuLeft -= cost
 		// Execute
 		switch ins.Op() {
 		case OpLdxb:
@@ -150,7 +294,7 @@ mainLoop:
 			if src := uint32(r[ins.Src()]); src != 0 {
 				r[ins.Dst()] = uint64(uint32(r[ins.Dst()]) / src)
 			} else {
-				return ExcDivideByZero
+				err = ExcDivideByZero
 			}
 		case OpDiv64Imm:
 			r[ins.Dst()] /= uint64(ins.Imm())
@@ -286,6 +430,110 @@ mainLoop:
 			default:
 				panic("invalid be instruction")
 			}
+		case OpUhmul64Imm:
+			r[ins.Dst()], _ = bits.Mul64(r[ins.Dst()], uint64(ins.Uimm()))
+		case OpUhmul64Reg:
+			r[ins.Dst()], _ = bits.Mul64(r[ins.Dst()], r[ins.Src()])
+		case OpShmul64Imm:
+			r[ins.Dst()] = mulHi64(int64(r[ins.Dst()]), int64(ins.Imm()))
+		case OpShmul64Reg:
+			r[ins.Dst()] = mulHi64(int64(r[ins.Dst()]), int64(r[ins.Src()]))
+		case OpLmul32Imm:
+			r[ins.Dst()] = uint64(int32(r[ins.Dst()]) * ins.Imm())
+		case OpLmul32Reg:
+			r[ins.Dst()] = uint64(int32(r[ins.Dst()]) * int32(r[ins.Src()]))
+		case OpLmul64Imm:
+			r[ins.Dst()] *= uint64(ins.Imm())
+		case OpLmul64Reg:
+			r[ins.Dst()] *= r[ins.Src()]
+		case OpUdiv32Imm:
+			r[ins.Dst()] = uint64(uint32(r[ins.Dst()]) / ins.Uimm())
+		case OpUdiv32Reg:
+			if src := uint32(r[ins.Src()]); src != 0 {
+				r[ins.Dst()] = uint64(uint32(r[ins.Dst()]) / src)
+			} else {
+				err = ExcDivideByZero
+			}
+		case OpUdiv64Imm:
+			r[ins.Dst()] /= uint64(ins.Uimm())
+		case OpUdiv64Reg:
+			if src := r[ins.Src()]; src != 0 {
+				r[ins.Dst()] /= src
+			} else {
+				err = ExcDivideByZero
+			}
+		case OpUrem32Imm:
+			r[ins.Dst()] = uint64(uint32(r[ins.Dst()]) % ins.Uimm())
+		case OpUrem32Reg:
+			if src := uint32(r[ins.Src()]); src != 0 {
+				r[ins.Dst()] = uint64(uint32(r[ins.Dst()]) % src)
+			} else {
+				err = ExcDivideByZero
+			}
+		case OpUrem64Imm:
+			r[ins.Dst()] %= uint64(ins.Uimm())
+		case OpUrem64Reg:
+			if src := r[ins.Src()]; src != 0 {
+				r[ins.Dst()] %= src
+			} else {
+				err = ExcDivideByZero
+			}
+		case OpPqrSdiv32Imm:
+			if int32(r[ins.Dst()]) == math.MinInt32 && ins.Imm() == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int32(r[ins.Dst()]) / ins.Imm())
+			}
+		case OpPqrSdiv32Reg:
+			if src := int32(r[ins.Src()]); src == 0 {
+				err = ExcDivideByZero
+			} else if int32(r[ins.Dst()]) == math.MinInt32 && src == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int32(r[ins.Dst()]) / src)
+			}
+		case OpPqrSdiv64Imm:
+			if int64(r[ins.Dst()]) == math.MinInt64 && ins.Imm() == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int64(r[ins.Dst()]) / int64(ins.Imm()))
+			}
+		case OpPqrSdiv64Reg:
+			if src := int64(r[ins.Src()]); src == 0 {
+				err = ExcDivideByZero
+			} else if int64(r[ins.Dst()]) == math.MinInt64 && src == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int64(r[ins.Dst()]) / src)
+			}
+		case OpSrem32Imm:
+			if int32(r[ins.Dst()]) == math.MinInt32 && ins.Imm() == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int32(r[ins.Dst()]) % ins.Imm())
+			}
+		case OpSrem32Reg:
+			if src := int32(r[ins.Src()]); src == 0 {
+				err = ExcDivideByZero
+			} else if int32(r[ins.Dst()]) == math.MinInt32 && src == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int32(r[ins.Dst()]) % src)
+			}
+		case OpSrem64Imm:
+			if int64(r[ins.Dst()]) == math.MinInt64 && ins.Imm() == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int64(r[ins.Dst()]) % int64(ins.Imm()))
+			}
+		case OpSrem64Reg:
+			if src := int64(r[ins.Src()]); src == 0 {
+				err = ExcDivideByZero
+			} else if int64(r[ins.Dst()]) == math.MinInt64 && src == -1 {
+				err = ExcDivideOverflow
+			} else {
+				r[ins.Dst()] = uint64(int64(r[ins.Dst()]) % src)
+			}
 		case OpLddw:
 			r[ins.Dst()] = uint64(ins.Uimm()) | (uint64(ip.getSlot(pc+1).Uimm()) << 32)
 			pc++
@@ -380,11 +628,19 @@ mainLoop:
 				pc += int64(ins.Off())
 			}
 		case OpCall:
-			// TODO use src reg hint
-			if sc, ok := ip.syscalls[ins.Uimm()]; ok {
+			if ip.version.StaticSyscalls() && ins.Src() == 1 {
+				// Internal call with relative target
+				var ok bool
+				r[10], ok = ip.stack.Push((*[4]uint64)(r[6:10]), r[10], pc+1)
+				if !ok {
+					err = ExcCallDepth
+				}
+				pc += int64(ins.Imm())
+			} else if sc, ok := ip.syscalls[ins.Uimm()]; ok {
+				ip.traceEv.Syscall = true
 				r[0], cuLeft, err = sc.Invoke(ip, r[1], r[2], r[3], r[4], r[5], cuLeft)
-			} else if target, ok := ip.funcs[ins.Uimm()]; ok {
-				r[10], ok = ip.stack.Push((*[4]uint64)(r[6:10]), pc+1)
+			} else if target, ok := ip.funcs[ins.Uimm()]; ok && !ip.version.StaticSyscalls() {
+				r[10], ok = ip.stack.Push((*[4]uint64)(r[6:10]), r[10], pc+1)
 				if !ok {
 					err = ExcCallDepth
 				}
@@ -393,106 +649,206 @@ mainLoop:
 				err = ExcCallDest{ins.Uimm()}
 			}
 		case OpCallx:
-			target := r[ins.Uimm()]
+			reg := ins.Uimm()
+			if ip.version.CallxUsesSrcReg() {
+				reg = uint32(ins.Src())
+			}
+			target := r[reg]
 			target &= ^(uint64(0x7))
 			var ok bool
-			r[10], ok = ip.stack.Push((*[4]uint64)(r[6:10]), pc+1)
+			r[10], ok = ip.stack.Push((*[4]uint64)(r[6:10]), r[10], pc+1)
 			if !ok {
 				err = ExcCallDepth
 			}
 			if target < ip.textVA || target >= VaddrStack || target >= ip.textVA+uint64(len(ip.text)) {
-				err = NewExcBadAccess(target, 8, false, "jump out-of-bounds")
+				err = ExcCallOutsideText
 			}
 			pc = int64((target-ip.textVA)/8) - 1
 		case OpExit:
+			var retPC int64
 			var ok bool
-			r[10], pc, ok = ip.stack.Pop((*[4]uint64)(r[6:10]))
+			r[10], retPC, ok = ip.stack.Pop((*[4]uint64)(r[6:10]))
 			if !ok {
-				break mainLoop
+				ip.exitPC = pc
+				if ip.trace != nil {
+					ip.traceAfter(&r, cuLeft, nil)
+				}
+				if ip.prof != nil {
+					ip.prof.end(cuLeft)
+				}
+				return ip.result(r[0], cuLeft, i+1), nil
 			}
-			pc--
+			pc = retPC - 1
 		default:
-			panic(fmt.Sprintf("unimplemented opcode %#02x", ins.Op()))
+			// Only reachable by calling into the middle of an lddw instruction
+			err = ExcInvalidInstruction
 		}
 		// Post execute
 		if cuLeft < 0 {
 			err = ExcOutOfCU
 		}
+		if ip.trace != nil {
+			ip.traceAfter(&r, cuLeft, err)
+		}
+		if ip.prof != nil {
+			ip.prof.end(cuLeft)
+		}
 		if err != nil {
-			exc := &Exception{
-				PC:     pc,
-				Detail: err,
-			}
 			if IsLongIns(ins.Op()) {
-				exc.PC-- // fix reported PC
+				pc-- // fix reported PC
 			}
-			return exc
+			return ip.result(r[0], cuLeft, i+1), ip.exception(pc, err)
 		}
 		pc++
 	}
+}
 
+// interrupted returns an exception if the execution was cancelled or timed out.
+func (ip *Interpreter) interrupted(done <-chan struct{}) error {
+	select {
+	case <-done:
+		return ExcCancelled
+	default:
+	}
+	if !ip.deadline.IsZero() && time.Now().After(ip.deadline) {
+		return ExcDeadline
+	}
 	return nil
 }
 
+// result summarizes the execution.
+func (ip *Interpreter) result(r0 uint64, cuLeft int, steps uint64) Result {
+	cuUsed := ip.cuMax - cuLeft
+	if cuLeft < 0 {
+		cuUsed = ip.cuMax
+	}
+	res := Result{
+		R0:           r0,
+		CUUsed:       cuUsed,
+		CUMax:        ip.cuMax,
+		Instructions: steps,
+		Heap:         ip.heap,
+	}
+	regions := ip.mem.Regions()
+	for i := range regions {
+		if regions[i].Vaddr >= VaddrInput {
+			res.Input = regions[i:]
+			break
+		}
+	}
+	return res
+}
+
+// zero clears a buffer.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func newStack(v Version) Stack {
+	if v.DynamicStackFrames() {
+		return NewDynamicStack()
+	}
+	return NewStack()
+}
+
+// mulHi64 returns the high 64 bits of the signed 128-bit product.
+func mulHi64(a, b int64) uint64 {
+	hi, _ := bits.Mul64(uint64(a), uint64(b))
+	if a < 0 {
+		hi -= uint64(b)
+	}
+	if b < 0 {
+		hi -= uint64(a)
+	}
+	return hi
+}
+
+// exception creates an exception raised at the given instruction, with a backtrace.
+func (ip *Interpreter) exception(pc int64, err error) *Exception {
+	return &Exception{
+		PC:     pc,
+		Detail: err,
+		Stack:  ip.program.Backtrace(pc, ip.stack.shadow),
+	}
+}
+
 func (ip *Interpreter) getSlot(pc int64) Slot {
 	return GetSlot(ip.text[pc*SlotSize:])
 }
 
+func (ip *Interpreter) traceBefore(step uint64, pc int64, ins Slot, r *[11]uint64, cuLeft int) {
+	ev := &ip.traceEv
+	ev.Step = step
+	ev.PC = pc
+	ev.Slot = ins
+	ev.Slot2 = 0
+	if IsLongIns(ins.Op()) {
+		ev.Slot2 = ip.getSlot(pc + 1)
+	}
+	ev.Before = *r
+	ev.CUBefore = cuLeft
+	ev.Syscall = false
+	ev.Mem = ev.Mem[:0]
+}
+
+func (ip *Interpreter) traceAfter(r *[11]uint64, cuLeft int, err error) {
+	ev := &ip.traceEv
+	ev.After = *r
+	ev.CUAfter = cuLeft
+	ev.Err = err
+	ip.trace.TraceIns(ev)
+}
+
+// traceMem records a memory access if tracing is enabled.
+func (ip *Interpreter) traceMem(addr uint64, size uint32, write bool, value uint64) {
+	if ip.trace != nil {
+		ip.traceEv.Mem = append(ip.traceEv.Mem, MemAccess{
+			Addr:  addr,
+			Size:  size,
+			Write: write,
+			Value: value,
+		})
+	}
+}
+
 func (ip *Interpreter) VMContext() any {
 	return ip.vmContext
 }
 
+func (ip *Interpreter) Costs() *CostTable {
+	return ip.costs
+}
+
+func (ip *Interpreter) HeapSize() int {
+	return len(ip.heap)
+}
+
+// translateInternal translates an access of a non-zero size.
 func (ip *Interpreter) translateInternal(addr uint64, size uint32, write bool) (unsafe.Pointer, error) {
-	// TODO exhaustive testing against rbpf
-	// TODO review generated asm for performance
-
-	hi, lo := addr>>32, addr&math.MaxUint32
-	switch hi {
-	case VaddrProgram >> 32:
-		if write {
-			return nil, NewExcBadAccess(addr, size, write, "write to program")
-		}
-		if lo+uint64(size) >= uint64(len(ip.ro)) {
-			return nil, NewExcBadAccess(addr, size, write, "out-of-bounds program read")
-		}
-		return unsafe.Pointer(&ip.ro[lo]), nil
-	case VaddrStack >> 32:
-		mem := ip.stack.GetFrame(uint32(addr))
-		if uint32(len(mem)) < size {
-			return nil, NewExcBadAccess(addr, size, write, "out-of-bounds stack access")
-		}
-		return unsafe.Pointer(&mem[0]), nil
-	case VaddrHeap >> 32:
-		if lo+uint64(size) >= uint64(len(ip.heap)) {
-			return nil, NewExcBadAccess(addr, size, write, "out-of-bounds heap access")
-		}
-		return unsafe.Pointer(&ip.heap[lo]), nil
-	case VaddrInput >> 32:
-		if lo+uint64(size) >= uint64(len(ip.input)) {
-			return nil, NewExcBadAccess(addr, size, write, "out-of-bounds input access")
-		}
-		return unsafe.Pointer(&ip.input[lo]), nil
-	default:
-		return nil, NewExcBadAccess(addr, size, write, "unmapped region")
+	mem, err := ip.mem.Translate(addr, size, write)
+	if err != nil {
+		return nil, err
 	}
+	return unsafe.Pointer(&mem[0]), nil
 }
 
 func (ip *Interpreter) Translate(addr uint64, size uint32, write bool) ([]byte, error) {
-	ptr, err := ip.translateInternal(addr, size, write)
+	mem, err := ip.mem.Translate(addr, size, write)
 	if err != nil {
 		return nil, err
 	}
-
-	mem := unsafe.Slice((*uint8)(ptr), size)
+	ip.traceMem(addr, size, write, 0)
 	return mem, nil
 }
 
 func (ip *Interpreter) Read(addr uint64, p []byte) error {
-	ptr, err := ip.translateInternal(addr, uint32(len(p)), false)
+	mem, err := ip.mem.Translate(addr, uint32(len(p)), false)
 	if err != nil {
 		return err
 	}
-	mem := unsafe.Slice((*uint8)(ptr), len(p))
+	ip.traceMem(addr, uint32(len(p)), false, 0)
 	copy(p, mem)
 	return nil
 }
@@ -502,7 +858,9 @@ func (ip *Interpreter) Read8(addr uint64) (uint8, error) {
 	if err != nil {
 		return 0, err
 	}
-	return *(*uint8)(ptr), nil
+	x := *(*uint8)(ptr)
+	ip.traceMem(addr, 1, false, uint64(x))
+	return x, nil
 }
 
 // TODO is it safe and portable to deref unaligned integer types?
@@ -512,7 +870,9 @@ func (ip *Interpreter) Read16(addr uint64) (uint16, error) {
 	if err != nil {
 		return 0, err
 	}
-	return *(*uint16)(ptr), nil
+	x := *(*uint16)(ptr)
+	ip.traceMem(addr, 2, false, uint64(x))
+	return x, nil
 }
 
 func (ip *Interpreter) Read32(addr uint64) (uint32, error) {
@@ -520,7 +880,9 @@ func (ip *Interpreter) Read32(addr uint64) (uint32, error) {
 	if err != nil {
 		return 0, err
 	}
-	return *(*uint32)(ptr), nil
+	x := *(*uint32)(ptr)
+	ip.traceMem(addr, 4, false, uint64(x))
+	return x, nil
 }
 
 func (ip *Interpreter) Read64(addr uint64) (uint64, error) {
@@ -528,15 +890,17 @@ func (ip *Interpreter) Read64(addr uint64) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return *(*uint64)(ptr), nil
+	x := *(*uint64)(ptr)
+	ip.traceMem(addr, 8, false, uint64(x))
+	return x, nil
 }
 
 func (ip *Interpreter) Write(addr uint64, p []byte) error {
-	ptr, err := ip.translateInternal(addr, uint32(len(p)), true)
+	mem, err := ip.mem.Translate(addr, uint32(len(p)), true)
 	if err != nil {
 		return err
 	}
-	mem := unsafe.Slice((*uint8)(ptr), len(p))
+	ip.traceMem(addr, uint32(len(p)), true, 0)
 	copy(mem, p)
 	return nil
 }
@@ -547,6 +911,7 @@ func (ip *Interpreter) Write8(addr uint64, x uint8) error {
 		return err
 	}
 	*(*uint8)(ptr) = x
+	ip.traceMem(addr, 1, true, uint64(x))
 	return nil
 }
 
@@ -556,6 +921,7 @@ func (ip *Interpreter) Write16(addr uint64, x uint16) error {
 		return err
 	}
 	*(*uint16)(ptr) = x
+	ip.traceMem(addr, 2, true, uint64(x))
 	return nil
 }
 
@@ -565,14 +931,16 @@ func (ip *Interpreter) Write32(addr uint64, x uint32) error {
 		return err
 	}
 	*(*uint32)(ptr) = x
+	ip.traceMem(addr, 4, true, uint64(x))
 	return nil
 }
 
 func (ip *Interpreter) Write64(addr uint64, x uint64) error {
-	ptr, err := ip.translateInternal(addr, 8, false)
+	ptr, err := ip.translateInternal(addr, 8, true)
 	if err != nil {
 		return err
 	}
 	*(*uint64)(ptr) = x
+	ip.traceMem(addr, 8, true, uint64(x))
 	return nil
 }
@@ -0,0 +1,269 @@
+package sbpf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Reads the stack and heap before writing to them
+const resetTestAsm = `
+	ldxdw r1, [r10-8]
+	call result
+	lddw r2, 0x300000000
+	ldxdw r1, [r2+0]
+	call result
+	mov64 r1, 42
+	stxdw [r10-8], r1
+	stxdw [r2+0], r1
+	exit
+`
+
+// Loops 1000 times
+const benchAsm = `
+	mov64 r1, 1000
+loop:
+	stxdw [r10-8], r1
+	ldxdw r2, [r10-8]
+	sub64 r1, 1
+	jne r1, 0, loop
+	exit
+`
+
+func assembleVerified(t testing.TB, version Version, src string, syscalls SyscallRegistry) *Program {
+	program, err := AssembleVersion(src, version)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(syscalls))
+	return program
+}
+
+func TestInterpreter_Reset(t *testing.T) {
+	var results []uint64
+	syscalls := NewSyscallRegistry()
+	syscalls.Register("result", SyscallFunc1(func(_ VM, r1 uint64, cuIn int) (uint64, int, error) {
+		results = append(results, r1)
+		return 0, cuIn, nil
+	}))
+	opts := &VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: syscalls,
+	}
+
+	ip := NewInterpreter(assembleVerified(t, VersionV1, resetTestAsm, syscalls), opts)
+	for _, version := range []Version{VersionV1, VersionV1, VersionV2} {
+		ip.Reset(assembleVerified(t, version, resetTestAsm, syscalls), opts)
+		results = nil
+		_, err := ip.Run()
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 0}, results, "memory not cleared for %s", version)
+	}
+
+	// Growing the heap
+	opts.HeapSize = 64 * 1024
+	ip.Reset(assembleVerified(t, VersionV1, resetTestAsm, syscalls), opts)
+	_, err := ip.Run()
+	require.NoError(t, err)
+	assert.Len(t, ip.heap, 64*1024)
+}
+
+func TestInterpreter_ResetAllocs(t *testing.T) {
+	program := assembleVerified(t, VersionV1, benchAsm, SyscallRegistry{})
+	opts := &VMOpts{HeapSize: 32 * 1024, MaxCU: 10000}
+	ip := NewInterpreter(program, opts)
+	allocs := testing.AllocsPerRun(10, func() {
+		ip.Reset(program, opts)
+		if _, err := ip.Run(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestInterpreter_Pool(t *testing.T) {
+	program := assembleVerified(t, VersionV1, benchAsm, SyscallRegistry{})
+	opts := &VMOpts{HeapSize: 32 * 1024, MaxCU: 10000}
+	for i := 0; i < 3; i++ {
+		ip := GetInterpreter(program, opts)
+		_, err := ip.Run()
+		require.NoError(t, err)
+		PutInterpreter(ip)
+	}
+}
+
+func BenchmarkInterpreter_New(b *testing.B) {
+	program := assembleVerified(b, VersionV1, benchAsm, SyscallRegistry{})
+	opts := &VMOpts{HeapSize: 32 * 1024, MaxCU: 10000}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewInterpreter(program, opts).Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpreter_Reset(b *testing.B) {
+	program := assembleVerified(b, VersionV1, benchAsm, SyscallRegistry{})
+	opts := &VMOpts{HeapSize: 32 * 1024, MaxCU: 10000}
+	ip := NewInterpreter(program, opts)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ip.Reset(program, opts)
+		if _, err := ip.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpreter_Pool(b *testing.B) {
+	program := assembleVerified(b, VersionV1, benchAsm, SyscallRegistry{})
+	opts := &VMOpts{HeapSize: 32 * 1024, MaxCU: 10000}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ip := GetInterpreter(program, opts)
+			if _, err := ip.Run(); err != nil {
+				b.Fatal(err)
+			}
+			PutInterpreter(ip)
+		}
+	})
+}
+
+func TestInterpreter_Result(t *testing.T) {
+	program := assembleVerified(t, VersionV1, `
+		lddw r2, 0x300000000
+		stb [r2+0], 0xab
+		stb [r1+1], 0xcd
+		mov64 r0, 7
+		exit
+	`, SyscallRegistry{})
+	ip := NewInterpreter(program, &VMOpts{
+		HeapSize: 1024,
+		MaxCU:    10000,
+		Input:    make([]byte, 2),
+	})
+	res, err := ip.Run()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), res.R0)
+	assert.Equal(t, uint64(5), res.Instructions)
+	assert.Equal(t, 10000, res.CUMax)
+	assert.Equal(t, uint8(0xab), res.Heap[0])
+	require.Len(t, res.Input, 1)
+	assert.Equal(t, []byte{0, 0xcd}, res.Input[0].Data)
+}
+
+func TestInterpreter_ResultException(t *testing.T) {
+	program := assembleVerified(t, VersionV1, `
+		mov64 r0, 1
+		mov64 r1, 0
+		div64 r0, r1
+		exit
+	`, SyscallRegistry{})
+	res, err := NewInterpreter(program, &VMOpts{MaxCU: 10000}).Run()
+	assert.ErrorIs(t, err, ExcDivideByZero)
+	assert.Equal(t, uint64(1), res.R0)
+	assert.Equal(t, uint64(3), res.Instructions)
+}
+
+func TestInterpreter_Cancel(t *testing.T) {
+	program := assembleVerified(t, VersionV1, "ja -1", SyscallRegistry{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, err := NewInterpreter(program, &VMOpts{MaxCU: 10000}).RunContext(ctx)
+	assert.ErrorIs(t, err, ExcCancelled)
+	assert.Zero(t, res.Instructions%cancelCheckInterval)
+	assert.NotZero(t, res.Instructions)
+
+	// Already cancelled
+	res, err = NewInterpreter(program, &VMOpts{MaxCU: 10000}).RunContext(ctx)
+	assert.ErrorIs(t, err, ExcCancelled)
+	assert.Zero(t, res.Instructions)
+}
+
+func TestInterpreter_Deadline(t *testing.T) {
+	program := assembleVerified(t, VersionV1, "ja -1", SyscallRegistry{})
+	_, err := NewInterpreter(program, &VMOpts{
+		MaxCU:    10000,
+		Deadline: time.Now().Add(10 * time.Millisecond),
+	}).Run()
+	assert.ErrorIs(t, err, ExcDeadline)
+}
+
+func TestInterpreter_Costs(t *testing.T) {
+	program := assembleVerified(t, VersionV1, `
+		mov64 r0, 1
+		mul64 r0, 3
+		stxdw [r10-8], r0
+		exit
+	`, SyscallRegistry{})
+	costs := NewCostTable(1)
+	costs.Opcodes[OpMul64Imm] = 5
+
+	res, err := NewInterpreter(program, &VMOpts{MaxCU: 8, Costs: costs}).Run()
+	require.NoError(t, err)
+	assert.Equal(t, 8, res.CUUsed)
+	assert.Equal(t, uint64(3), res.R0)
+
+	// Runs out of compute units before the store
+	ip := NewInterpreter(program, &VMOpts{MaxCU: 6, Costs: costs})
+	res, err = ip.Run()
+	var exc *Exception
+	require.ErrorAs(t, err, &exc)
+	assert.ErrorIs(t, err, ExcOutOfCU)
+	assert.Equal(t, int64(2), exc.PC)
+	assert.Equal(t, 6, res.CUUsed)
+	assert.Equal(t, uint64(2), res.Instructions)
+	assert.Zero(t, ip.stack.mem[StackFrameSize-8])
+
+	// No instruction costs without a cost table
+	res, err = NewInterpreter(program, &VMOpts{MaxCU: 0}).Run()
+	require.NoError(t, err)
+	assert.Zero(t, res.CUUsed)
+}
+
+func TestInterpreter_HeapCost(t *testing.T) {
+	program := assembleVerified(t, VersionV1, "exit", SyscallRegistry{})
+	costs := NewCostTable(1)
+	costs.HeapPageCost = 8
+	assert.Zero(t, costs.Heap(0))
+	assert.Zero(t, costs.Heap(32*1024))
+	assert.Equal(t, uint64(8), costs.Heap(32*1024+1))
+	assert.Equal(t, uint64(56), costs.Heap(256*1024))
+
+	// Charged before the first instruction
+	res, err := NewInterpreter(program, &VMOpts{HeapSize: 64 * 1024, MaxCU: 100, Costs: costs}).Run()
+	require.NoError(t, err)
+	assert.Equal(t, 9, res.CUUsed)
+
+	res, err = NewInterpreter(program, &VMOpts{HeapSize: 64 * 1024, MaxCU: 7, Costs: costs}).Run()
+	assert.ErrorIs(t, err, ExcOutOfCU)
+	assert.Equal(t, 7, res.CUUsed)
+	assert.Zero(t, res.Instructions)
+}
+
+func TestInterpreter_ExecutionOverrun(t *testing.T) {
+	// Calls at the end of the text pass verification, as they may not return
+	_, err := runAsm(t, `
+		ja skip
+	fn:
+		exit
+	skip:
+		call fn
+	`)
+	assert.ErrorIs(t, err, ExcExecutionOverrun)
+}
+
+func TestInterpreter_InvalidInstruction(t *testing.T) {
+	_, err := runAsm(t, `
+		lddw r1, 0x100000008
+		callx r1
+		exit
+	`)
+	assert.ErrorIs(t, err, ExcInvalidInstruction)
+}
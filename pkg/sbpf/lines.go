@@ -0,0 +1,92 @@
+package sbpf
+
+import (
+	"sort"
+	"sync"
+)
+
+// LineTable maps instructions to source code locations.
+type LineTable struct {
+	once sync.Once
+	read func() []LineRow // reads rows on first use, if set
+	rows []LineRow        // sorted by PC
+}
+
+// LineRow is the source location of the instructions starting at PC.
+//
+// A row with an empty File marks the end of a sequence of instructions with known locations.
+type LineRow struct {
+	PC   int64
+	File string
+	Line int
+}
+
+// NewLineTable creates a line table from the given rows.
+//
+// If multiple rows have the same PC, the last one wins,
+// except that end of sequence rows never replace rows with a location.
+func NewLineTable(rows []LineRow) *LineTable {
+	return &LineTable{rows: sortLineRows(rows)}
+}
+
+// NewLazyLineTable creates a line table from rows read on first lookup.
+//
+// Allows deferring the parsing of debug info until it is needed.
+func NewLazyLineTable(read func() []LineRow) *LineTable {
+	return &LineTable{read: read}
+}
+
+func (t *LineTable) init() {
+	t.once.Do(func() {
+		if t.read != nil {
+			t.rows = sortLineRows(t.read())
+			t.read = nil
+		}
+	})
+}
+
+// sortLineRows sorts a copy of rows by PC, keeping the last row of each PC.
+//
+// End of sequence rows are only kept if no other row has the same PC,
+// since a sequence of one compilation unit may end where the next one starts.
+func sortLineRows(rows []LineRow) []LineRow {
+	rows = append([]LineRow(nil), rows...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].PC < rows[j].PC })
+	dedup := rows[:0]
+	for _, row := range rows {
+		if n := len(dedup); n > 0 && dedup[n-1].PC == row.PC {
+			if row.File != "" || dedup[n-1].File == "" {
+				dedup[n-1] = row
+			}
+			continue
+		}
+		dedup = append(dedup, row)
+	}
+	return dedup
+}
+
+// Lookup returns the source location of the instruction at pc.
+func (t *LineTable) Lookup(pc int64) (file string, line int, ok bool) {
+	if t == nil {
+		return "", 0, false
+	}
+	t.init()
+	i := sort.Search(len(t.rows), func(i int) bool { return t.rows[i].PC > pc })
+	if i == 0 {
+		return "", 0, false
+	}
+	row := t.rows[i-1]
+	if row.File == "" {
+		return "", 0, false
+	}
+	return row.File, row.Line, true
+}
+
+// Rows returns the rows of the line table, sorted by PC.
+func (t *LineTable) Rows() []LineRow {
+	if t == nil {
+		return nil
+	}
+	t.init()
+	return t.rows
+}
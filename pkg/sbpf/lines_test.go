@@ -0,0 +1,37 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineTable_EndSequence(t *testing.T) {
+	// The sequence of b.c starts where the sequence of a.c ends.
+	table := NewLineTable([]LineRow{
+		{PC: 0, File: "a.c", Line: 1},
+		{PC: 4, File: "b.c", Line: 7},
+		{PC: 4},
+		{PC: 8, File: "b.c", Line: 8},
+		{PC: 10},
+	})
+
+	cases := []struct {
+		pc   int64
+		file string
+		line int
+		ok   bool
+	}{
+		{0, "a.c", 1, true},
+		{3, "a.c", 1, true},
+		{4, "b.c", 7, true},
+		{9, "b.c", 8, true},
+		{10, "", 0, false},
+	}
+	for _, c := range cases {
+		file, line, ok := table.Lookup(c.pc)
+		assert.Equal(t, c.file, file, "pc %d", c.pc)
+		assert.Equal(t, c.line, line, "pc %d", c.pc)
+		assert.Equal(t, c.ok, ok, "pc %d", c.pc)
+	}
+}
@@ -17,6 +17,7 @@ import (
 func (l *Loader) copy() error {
 	l.progRange = newAddrRange()
 	l.rodatas = make([]addrRange, 0, 4)
+	l.rodataOffs = make([]uint64, 0, 4)
 	if err := l.getText(); err != nil {
 		return err
 	}
@@ -34,7 +35,7 @@ func (l *Loader) getText() error {
 	if err := l.checkSectionAddrs(l.shText); err != nil {
 		return fmt.Errorf("invalid .text: %w", err)
 	}
-	l.textRange = addrRange{min: l.shText.Off, max: l.shText.Off + l.shText.Size}
+	l.textRange = l.sectionRange(l.shText)
 	return nil
 }
 
@@ -62,7 +63,7 @@ func (l *Loader) mapSections() error {
 		}
 
 		// Section overlap check & bounds tracking
-		section := addrRange{min: sh.Off, max: sh.Off + sh.Size}
+		section := l.sectionRange(&sh)
 		if section.len() == 0 {
 			continue
 		}
@@ -74,23 +75,22 @@ func (l *Loader) mapSections() error {
 
 		if section.min != l.textRange.min {
 			l.rodatas = append(l.rodatas, section)
+			l.rodataOffs = append(l.rodataOffs, sh.Off)
 		}
 	}
 	return iter.Err()
 }
 
 func (l *Loader) checkSectionAddrs(sh *elf.Section64) error {
-	// TODO Support true vaddr ELFs
-
 	if sh.Size > l.fileSize {
 		return io.ErrUnexpectedEOF
 	}
-	if sh.Addr != sh.Off {
+	if sh.Addr != sh.Off && !l.version.EnableElfVaddr() {
 		return fmt.Errorf("section physical address out-of-place")
 	}
 
 	// Ensure section within VM program range
-	vaddr := clampAddUint64(sbpf.VaddrProgram, sh.Addr)
+	vaddr := clampAddUint64(sbpf.VaddrProgram, l.progOff(sh.Addr))
 	vaddrEnd := vaddr + sh.Size
 	if vaddrEnd < vaddr || vaddrEnd > sbpf.VaddrStack {
 		return fmt.Errorf("section virtual address out-of-bounds")
@@ -107,16 +107,22 @@ func (l *Loader) copySections() error {
 	}
 	l.progRange.extendToFit(0)
 
+	// Sections of SBFv2 programs may be placed at any address,
+	// so bound the buffer by the file size like rbpf.
+	if l.progRange.len() > l.fileSize {
+		return fmt.Errorf("program buffer larger than file")
+	}
+
 	// Allocate!
 	l.program = make([]byte, l.progRange.len())
 
 	// Read data from ELF file
-	for _, section := range l.rodatas {
-		if err := l.copySection(section); err != nil {
+	for i, section := range l.rodatas {
+		if err := l.copySection(section, l.rodataOffs[i]); err != nil {
 			return err
 		}
 	}
-	if err := l.copySection(l.textRange); err != nil {
+	if err := l.copySection(l.textRange, l.shText.Off); err != nil {
 		return err
 	}
 
@@ -126,13 +132,30 @@ func (l *Loader) copySections() error {
 	return nil
 }
 
-func (l *Loader) copySection(section addrRange) (err error) {
-	off, size := int64(section.min), int64(section.len())
-	rd := io.NewSectionReader(l.rd, off, size)
+// copySection reads the section at the given file offset into the program buffer.
+func (l *Loader) copySection(section addrRange, off uint64) (err error) {
+	rd := io.NewSectionReader(l.rd, int64(off), int64(section.len()))
 	_, err = io.ReadFull(rd, l.program[section.min:section.max])
 	return
 }
 
+// progOff converts an ELF virtual address into an offset into the program buffer.
+//
+// SBFv1 programs are linked at address zero, with virtual addresses matching file offsets.
+// SBFv2 programs may also be linked at VaddrProgram.
+func (l *Loader) progOff(vaddr uint64) uint64 {
+	if l.version.EnableElfVaddr() && vaddr >= sbpf.VaddrProgram {
+		return vaddr - sbpf.VaddrProgram
+	}
+	return vaddr
+}
+
+// sectionRange returns the range of a section in the program buffer.
+func (l *Loader) sectionRange(sh *elf.Section64) addrRange {
+	min := l.progOff(sh.Addr)
+	return addrRange{min: min, max: min + sh.Size}
+}
+
 func (l *Loader) getRange(section addrRange) []byte {
 	return l.program[section.min:section.max]
 }
@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"errors"
+	"io"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// getDebugInfo sets up the DWARF line table if the ELF contains debug info (.debug_info, .debug_line).
+//
+// The line table is only read on first use, and like symbols, malformed DWARF data is ignored.
+func (l *Loader) getDebugInfo() {
+	if l.shDbgInfo == nil || l.shDbgLine == nil {
+		return
+	}
+	rd, text := l.rd, *l.shText
+	l.lines = sbpf.NewLazyLineTable(func() []sbpf.LineRow {
+		rows, _ := readLineTable(rd, &text)
+		return rows
+	})
+}
+
+// readLineTable reads the line table rows of all compile units covering the text section.
+func readLineTable(rd io.ReaderAt, text *elf.Section64) ([]sbpf.LineRow, error) {
+	f, err := elf.NewFile(rd)
+	if err != nil {
+		return nil, err
+	}
+	data, err := f.DWARF()
+	if err != nil {
+		return nil, err
+	}
+	var rows []sbpf.LineRow
+	dr := data.Reader()
+	for {
+		cu, err := dr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if cu == nil {
+			return rows, nil
+		}
+		if cu.Tag != dwarf.TagCompileUnit {
+			dr.SkipChildren()
+			continue
+		}
+		lr, err := data.LineReader(cu)
+		if err != nil {
+			return nil, err
+		}
+		dr.SkipChildren()
+		if lr == nil {
+			continue
+		}
+		if rows, err = appendLineRows(rows, lr, text); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// appendLineRows appends the rows of a compile unit's line program within the text section.
+func appendLineRows(rows []sbpf.LineRow, lr *dwarf.LineReader, text *elf.Section64) ([]sbpf.LineRow, error) {
+	var entry dwarf.LineEntry
+	for {
+		if err := lr.Next(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return rows, nil
+			}
+			return nil, err
+		}
+		off := entry.Address - text.Addr
+		if entry.Address < text.Addr || off > text.Size || off%sbpf.SlotSize != 0 {
+			continue
+		}
+		row := sbpf.LineRow{PC: int64(off / sbpf.SlotSize)}
+		if !entry.EndSequence && entry.File != nil {
+			row.File = entry.File.Name
+			row.Line = entry.Line
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,52 @@
+package loader_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+	"go.firedancer.io/radiance/pkg/sealevel"
+)
+
+// FuzzLoader loads, verifies and runs programs with the Sealevel syscalls.
+//
+// Crashing inputs are kept in testdata/fuzz/FuzzLoader.
+func FuzzLoader(f *testing.F) {
+	files, err := filepath.Glob(fixtures.Path(f, "sbpf", "*.so"))
+	require.NoError(f, err)
+	for _, path := range files {
+		f.Add(fixtures.Load(f, "sbpf", filepath.Base(path)))
+	}
+
+	syscalls := sealevel.Syscalls()
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		ld, err := loader.NewLoaderFromBytes(buf)
+		if err != nil {
+			return
+		}
+		program, err := ld.Load()
+		if err != nil {
+			return
+		}
+		if err := program.Verify(syscalls); err != nil {
+			return
+		}
+
+		// Verified programs must not panic and may only fail with an *Exception
+		interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+			HeapSize: 32 * 1024,
+			Syscalls: syscalls,
+			Costs:    sealevel.DefaultCosts(),
+			MaxCU:    10_000,
+			Context:  &sealevel.Execution{Log: new(sealevel.LogCollector)},
+		})
+		if _, err := interpreter.Run(); err != nil {
+			if _, ok := err.(*sbpf.Exception); !ok {
+				t.Fatalf("unexpected error type %T: %s", err, err)
+			}
+		}
+	})
+}
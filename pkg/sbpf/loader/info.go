@@ -0,0 +1,137 @@
+package loader
+
+import (
+	"debug/elf"
+	"sort"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// Info is a read-only view of the ELF metadata of a loaded program.
+type Info struct {
+	Version    sbpf.Version
+	Flags      uint32 // ELF header flags
+	EntryAddr  uint64 // ELF entry address
+	Entrypoint uint64 // PC
+	TextAddr   uint64 // ELF address of .text
+	TextSize   uint64
+	Sections   []SectionInfo
+	Exports    []SymbolInfo // defined dynamic symbols
+	Imports    []SymbolInfo // undefined dynamic symbols
+	Syscalls   []SymbolInfo // syscalls called by the program, sorted by hash, named if imported
+	Relocs     map[R_BPF]int
+	FuncCount  int // number of functions, including the entrypoint
+}
+
+// SectionInfo describes an ELF section.
+type SectionInfo struct {
+	Name  string
+	Type  elf.SectionType
+	Flags elf.SectionFlag
+	Addr  uint64
+	Off   uint64
+	Size  uint64
+}
+
+// SymbolInfo describes a symbol or syscall.
+type SymbolInfo struct {
+	Name string // empty if unknown
+	Hash uint32 // symbol hash, as used in call instructions
+	Addr uint64 // ELF address, zero if undefined
+}
+
+// Info returns the ELF metadata of the program.
+//
+// Only valid after Load succeeded.
+func (l *Loader) Info() *Info {
+	info := &Info{
+		Version:    l.version,
+		Flags:      l.eh.Flags,
+		EntryAddr:  l.eh.Entry,
+		Entrypoint: l.entrypoint,
+		TextAddr:   l.shText.Addr,
+		TextSize:   l.shText.Size,
+		Sections:   l.sectionInfos(),
+		Relocs:     l.relocCounts,
+	}
+	info.Exports, info.Imports = l.dynSymbolInfos()
+	info.Syscalls = l.syscallInfos()
+
+	funcs := map[int64]struct{}{int64(l.entrypoint): {}}
+	for _, pc := range l.funcs {
+		funcs[pc] = struct{}{}
+	}
+	info.FuncCount = len(funcs)
+	return info
+}
+
+func (l *Loader) sectionInfos() []SectionInfo {
+	var sections []SectionInfo
+	iter := l.newShTableIter()
+	for iter.Next() && iter.Err() == nil {
+		if iter.Index() == 0 {
+			continue // SHT_NULL
+		}
+		sh := iter.Item()
+		name, _ := l.getString(&l.shShstrtab, sh.Name, maxSectionNameLen)
+		sections = append(sections, SectionInfo{
+			Name:  name,
+			Type:  elf.SectionType(sh.Type),
+			Flags: elf.SectionFlag(sh.Flags),
+			Addr:  sh.Addr,
+			Off:   sh.Off,
+			Size:  sh.Size,
+		})
+	}
+	return sections
+}
+
+func (l *Loader) dynSymbolInfos() (exports []SymbolInfo, imports []SymbolInfo) {
+	if l.shDynsym == nil || l.shDynstr == nil {
+		return
+	}
+	iter, err := l.getSymtab(l.shDynsym)
+	if err != nil {
+		return
+	}
+	for iter.Next() && iter.Err() == nil {
+		sym := iter.Item()
+		name, err := l.getString(l.shDynstr, sym.Name, maxSymbolNameLen)
+		if err != nil || name == "" {
+			continue
+		}
+		info := SymbolInfo{Name: name, Hash: sbpf.SymbolHash(name), Addr: sym.Value}
+		if elf.SectionIndex(sym.Shndx) == elf.SHN_UNDEF {
+			imports = append(imports, info)
+		} else {
+			exports = append(exports, info)
+		}
+	}
+	return
+}
+
+// syscallInfos collects the syscalls called by the program text.
+func (l *Loader) syscallInfos() []SymbolInfo {
+	hashes := make(map[uint32]struct{})
+	for i := 0; i+sbpf.SlotSize <= len(l.text); i += sbpf.SlotSize {
+		slot := sbpf.GetSlot(l.text[i:])
+		if slot.Op() != sbpf.OpCall {
+			continue
+		}
+		if l.version.StaticSyscalls() {
+			if slot.Src() != 0 {
+				continue
+			}
+		} else if _, ok := l.funcs[slot.Uimm()]; ok {
+			continue
+		}
+		hashes[slot.Uimm()] = struct{}{}
+	}
+
+	syscalls := make([]SymbolInfo, 0, len(hashes))
+	for hash := range hashes {
+		syscalls = append(syscalls, SymbolInfo{Name: l.imports[hash], Hash: hash})
+	}
+	sort.Slice(syscalls, func(i, j int) bool { return syscalls[i].Hash < syscalls[j].Hash })
+	return syscalls
+}
@@ -12,7 +12,6 @@ import (
 	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
-// TODO Fuzz
 // TODO Differential fuzz against rbpf
 
 // Loader is based on solana_rbpf::elf_parser
@@ -23,6 +22,7 @@ type Loader struct {
 
 	// ELF data structures
 	eh         elf.Header64
+	version    sbpf.Version
 	phLoad     elf.Prog64
 	phDynamic  *elf.Prog64
 	shShstrtab elf.Section64
@@ -32,15 +32,18 @@ type Loader struct {
 	shDynstr   *elf.Section64
 	shDynamic  *elf.Section64
 	shDynsym   *elf.Section64
+	shDbgInfo  *elf.Section64
+	shDbgLine  *elf.Section64
 	dynamic    [DT_NUM]uint64
 	relocsIter *tableIter[elf.Rel64]
 	dynSymIter *tableIter[elf.Sym64]
 
 	// Program section/segment mappings
-	// Uses physical addressing
-	rodatas   []addrRange
-	textRange addrRange
-	progRange addrRange
+	// Uses offsets into the program buffer (virtual address minus VaddrProgram)
+	rodatas    []addrRange
+	rodataOffs []uint64 // file offsets of rodatas
+	textRange  addrRange
+	progRange  addrRange
 
 	// Contains most of ELF (.text and rodata-like)
 	// Non-loaded sections are zeroed
@@ -49,7 +52,14 @@ type Loader struct {
 	entrypoint uint64 // program counter
 
 	// Symbols
-	funcs map[uint32]int64
+	funcs     map[uint32]int64
+	funcNames map[int64]string
+	imports   map[uint32]string // syscall names by hash
+
+	relocCounts map[R_BPF]int
+
+	// Debug info
+	lines *sbpf.LineTable
 }
 
 // Bounds checks
@@ -91,15 +101,27 @@ func (l *Loader) Load() (*sbpf.Program, error) {
 	if err := l.relocate(); err != nil {
 		return nil, err
 	}
+	l.getFuncNames()
+	l.getDebugInfo()
 	return l.getProgram(), nil
 }
 
+// Imports returns the names of syscalls referenced by relocations, keyed by symbol hash.
+//
+// Only valid after Load succeeded.
+func (l *Loader) Imports() map[uint32]string {
+	return l.imports
+}
+
 func (l *Loader) getProgram() *sbpf.Program {
 	return &sbpf.Program{
+		Version:    l.version,
 		RO:         l.program,
 		Text:       l.text,
 		TextVA:     sbpf.VaddrProgram + l.textRange.min,
 		Entrypoint: l.entrypoint,
 		Funcs:      l.funcs,
+		FuncNames:  l.funcNames,
+		Lines:      l.lines,
 	}
 }
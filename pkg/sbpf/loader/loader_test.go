@@ -3,11 +3,13 @@ package loader
 import (
 	"debug/elf"
 	_ "embed"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
 func TestLoader_Noop(t *testing.T) {
@@ -207,5 +209,203 @@ func TestVerifier(t *testing.T) {
 	program, err := loader.Load()
 	require.NoError(t, err)
 
-	require.NoError(t, program.Verify())
+	syscalls := sbpf.NewSyscallRegistry()
+	for _, name := range []string{"log", "log_64"} {
+		syscalls.Register(name, sbpf.SyscallFunc5(func(_ sbpf.VM, _, _, _, _, _ uint64, cuIn int) (uint64, int, error) {
+			return 0, cuIn, nil
+		}))
+	}
+	require.NoError(t, program.Verify(syscalls))
+}
+
+func TestLoader_Symbols(t *testing.T) {
+	loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", "relative_call.so"))
+	require.NoError(t, err)
+
+	program, err := loader.Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int64]string{
+		0: "syscall",
+		8: "entrypoint",
+	}, program.FuncNames)
+	assert.Equal(t, map[uint32]string{
+		sbpf.SymbolHash("log"): "log",
+	}, loader.Imports())
+}
+
+func TestLoader_LineTable(t *testing.T) {
+	loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", "relative_call.so"))
+	require.NoError(t, err)
+	program, err := loader.Load()
+	require.NoError(t, err)
+	assert.Nil(t, program.Lines)
+
+	loader, err = NewLoaderFromBytes(fixtures.Load(t, "sbpf", "relative_call_debug.so"))
+	require.NoError(t, err)
+	program, err = loader.Load()
+	require.NoError(t, err)
+	require.NotNil(t, program.Lines)
+
+	cases := []struct {
+		pc   int64
+		line int
+	}{
+		{0, 3},
+		{1, 4},
+		{4, 4},
+		{5, 5},
+		{7, 6},
+		{8, 9},
+		{13, 11},
+		{15, 12},
+	}
+	for _, c := range cases {
+		file, line, ok := program.Lines.Lookup(c.pc)
+		require.True(t, ok, "pc %d", c.pc)
+		assert.Equal(t, "/src/relative_call.c", file)
+		assert.Equal(t, c.line, line, "pc %d", c.pc)
+	}
+	_, _, ok := program.Lines.Lookup(16)
+	assert.False(t, ok)
+}
+
+func TestLoader_Backtrace(t *testing.T) {
+	loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", "relative_call_debug.so"))
+	require.NoError(t, err)
+	program, err := loader.Load()
+	require.NoError(t, err)
+	require.NotNil(t, program.Lines)
+
+	// Fail the log call in the "syscall" function.
+	errLog := errors.New("log failed")
+	syscalls := sbpf.NewSyscallRegistry()
+	syscalls.Register("log", sbpf.SyscallFunc2(func(_ sbpf.VM, _, size uint64, cuIn int) (uint64, int, error) {
+		if size == 8 {
+			return 0, cuIn, errLog
+		}
+		return 0, cuIn, nil
+	}))
+	require.NoError(t, program.Verify(syscalls))
+	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: syscalls,
+		Input:    make([]byte, 8),
+	})
+	_, err = interpreter.Run()
+	require.ErrorIs(t, err, errLog)
+
+	var exc *sbpf.Exception
+	require.ErrorAs(t, err, &exc)
+	assert.Equal(t, []sbpf.StackFrame{
+		{PC: 4, Func: "syscall", File: "/src/relative_call.c", Line: 4},
+		{PC: 14, Func: "entrypoint", File: "/src/relative_call.c", Line: 11},
+	}, exc.Stack)
+	assert.Equal(t, "exception at 4 in syscall /src/relative_call.c:4: log failed", err.Error())
+	assert.Equal(t, `#0 syscall (pc 4) at /src/relative_call.c:4
+#1 entrypoint (pc 14) at /src/relative_call.c:11
+`, exc.FormatStack())
+}
+
+func TestLoader_SBFv2(t *testing.T) {
+	cases := []struct {
+		file    string
+		version sbpf.Version
+		textVA  uint64
+		r0      uint64
+	}{
+		{"rodata_high_vaddr.so", sbpf.VersionV2, sbpf.VaddrProgram, 42},
+		{"reloc_64_64_high_vaddr.so", sbpf.VersionV2, sbpf.VaddrProgram, sbpf.VaddrProgram},
+		{"reloc_64_relative_high_vaddr.so", sbpf.VersionV2, sbpf.VaddrProgram, sbpf.VaddrProgram + 0x18},
+		{"reloc_64_relative_data_high_vaddr.so", sbpf.VersionV2, sbpf.VaddrProgram, sbpf.VaddrProgram + 0x20},
+		{"reloc_64_relative_data.so", sbpf.VersionV2, sbpf.VaddrProgram + 0xe8, sbpf.VaddrProgram + 0x108},
+		{"reloc_64_relative_data_pre_sbfv2.so", sbpf.VersionV1, sbpf.VaddrProgram + 0xe8, sbpf.VaddrProgram + 0x108},
+		{"syscall_static.so", sbpf.VersionV2, sbpf.VaddrProgram + 0xe8, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", c.file))
+			require.NoError(t, err)
+			program, err := loader.Load()
+			require.NoError(t, err)
+			assert.Equal(t, c.version, program.Version)
+			assert.Equal(t, c.textVA, program.TextVA)
+
+			var logged bool
+			syscalls := sbpf.NewSyscallRegistry()
+			syscalls.Register("log", sbpf.SyscallFunc2(func(_ sbpf.VM, _, _ uint64, cuIn int) (uint64, int, error) {
+				logged = true
+				return 0, cuIn, nil
+			}))
+			require.NoError(t, program.Verify(syscalls))
+			interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+				HeapSize: 32 * 1024,
+				MaxCU:    10000,
+				Syscalls: syscalls,
+			})
+			res, err := interpreter.Run()
+			require.NoError(t, err)
+			assert.Equal(t, c.r0, res.R0)
+			assert.Equal(t, c.file == "syscall_static.so", logged)
+		})
+	}
+}
+
+func TestLoader_SBFv2_UnknownSyscall(t *testing.T) {
+	loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", "syscall_static_unknown.so"))
+	require.NoError(t, err)
+	program, err := loader.Load()
+	require.NoError(t, err)
+	syscalls := sbpf.NewSyscallRegistry()
+	assert.ErrorIs(t, program.Verify(syscalls), sbpf.ExcCallDest{Imm: 42})
+
+	// Also rejected at runtime when the verifier is skipped
+	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: syscalls,
+	})
+	_, err = interpreter.Run()
+	assert.ErrorIs(t, err, sbpf.ExcCallDest{Imm: 42})
+}
+
+func TestLoader_Info(t *testing.T) {
+	loader, err := NewLoaderFromBytes(fixtures.Load(t, "sbpf", "memcpy_and_memmove_test_matched.so"))
+	require.NoError(t, err)
+	_, err = loader.Load()
+	require.NoError(t, err)
+
+	info := loader.Info()
+	assert.Equal(t, sbpf.VersionV1, info.Version)
+	assert.Equal(t, uint64(288), info.EntryAddr)
+	assert.Equal(t, uint64(0), info.Entrypoint)
+	assert.Equal(t, uint64(520), info.TextSize)
+	assert.Equal(t, 3, info.FuncCount)
+	require.Len(t, info.Sections, 10)
+	assert.Equal(t, SectionInfo{
+		Name:  ".text",
+		Type:  elf.SHT_PROGBITS,
+		Flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR,
+		Addr:  288,
+		Off:   288,
+		Size:  520,
+	}, info.Sections[0])
+	assert.Equal(t, []SymbolInfo{
+		{Name: "memcmp", Hash: sbpf.SymbolHash("memcmp"), Addr: 536},
+		{Name: "strlen", Hash: sbpf.SymbolHash("strlen"), Addr: 744},
+		{Name: "entrypoint", Hash: sbpf.SymbolHash("entrypoint"), Addr: 288},
+	}, info.Exports)
+	assert.Equal(t, []SymbolInfo{
+		{Name: "my_copy", Hash: sbpf.SymbolHash("my_copy")},
+		{Name: "sol_log_", Hash: sbpf.SymbolHash("sol_log_")},
+	}, info.Imports)
+	assert.Equal(t, []SymbolInfo{
+		{Name: "sol_log_", Hash: sbpf.SymbolHash("sol_log_")},
+		{Name: "my_copy", Hash: sbpf.SymbolHash("my_copy")},
+	}, info.Syscalls)
+	assert.Equal(t, map[R_BPF]int{
+		R_BPF_64_RELATIVE: 4,
+		R_BPF_64_32:       4,
+	}, info.Relocs)
 }
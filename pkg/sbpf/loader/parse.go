@@ -10,6 +10,8 @@ import (
 	"math"
 	"math/bits"
 	"strings"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
 // parse checks ELF file for validity and loads metadata with minimal allocations.
@@ -95,6 +97,9 @@ func (l *Loader) validateHeader() error {
 		eh.Shstrndx >= eh.Shnum {
 		return fmt.Errorf("invalid ELF file")
 	}
+	if eh.Flags == EF_SBF_V2 {
+		l.version = sbpf.VersionV2
+	}
 
 	if eh.Phoff < ehLen {
 		return fmt.Errorf("program header overlaps with file header")
@@ -211,6 +216,9 @@ func (l *Loader) readSectionHeaderTable() error {
 }
 
 func (l *Loader) getString(strtab *elf.Section64, stroff uint32, maxLen uint16) (string, error) {
+	if strtab == nil {
+		return "", fmt.Errorf("missing strtab")
+	}
 	if elf.SectionType(strtab.Type) != elf.SHT_STRTAB {
 		return "", fmt.Errorf("invalid strtab")
 	}
@@ -264,6 +272,10 @@ func (l *Loader) parseSections() error {
 			err = setSection(&l.shStrtab)
 		case ".dynstr":
 			err = setSection(&l.shDynstr)
+		case ".debug_info":
+			err = setSection(&l.shDbgInfo)
+		case ".debug_line":
+			err = setSection(&l.shDbgLine)
 		}
 		if err != nil {
 			return err
@@ -286,17 +298,24 @@ func (l *Loader) parseSections() error {
 	return iter.Err()
 }
 
+// newDynamicIter returns an iterator over the dynamic table.
+//
+// Prefers the PT_DYNAMIC segment, but falls back to the SHT_DYNAMIC section
+// if the segment is invalid (like rbpf).
 func (l *Loader) newDynamicIter() (*tableIter[elf.Dyn64], error) {
-	var off uint64
-	var size uint64
 	if ph := l.phDynamic; ph != nil {
-		off, size = ph.Off, ph.Filesz
-	} else if sh := l.shDynamic; sh != nil {
-		off, size = sh.Off, sh.Size
-	} else {
-		return nil, nil
+		iter, err := l.newDynamicIterAt(ph.Off, ph.Filesz)
+		if err == nil || l.shDynamic == nil {
+			return iter, err
+		}
+	}
+	if sh := l.shDynamic; sh != nil {
+		return l.newDynamicIterAt(sh.Off, sh.Size)
 	}
+	return nil, nil
+}
 
+func (l *Loader) newDynamicIterAt(off uint64, size uint64) (*tableIter[elf.Dyn64], error) {
 	if size%dynLen != 0 {
 		return nil, fmt.Errorf("odd .dynamic size")
 	}
@@ -304,7 +323,7 @@ func (l *Loader) newDynamicIter() (*tableIter[elf.Dyn64], error) {
 		return nil, io.ErrUnexpectedEOF
 	}
 
-	iter := newTableIterator[elf.Dyn64](l, off, uint32(off/dynLen), dynLen)
+	iter := newTableIterator[elf.Dyn64](l, off, uint32(size/dynLen), dynLen)
 	return iter, nil
 }
 
@@ -323,7 +342,7 @@ func (l *Loader) parseDynamicTable() error {
 		if dyn.Tag == int64(elf.DT_NULL) {
 			break
 		}
-		if dyn.Tag >= int64(len(l.dynamic)) {
+		if dyn.Tag < 0 || dyn.Tag >= int64(len(l.dynamic)) {
 			continue
 		}
 		l.dynamic[dyn.Tag] = dyn.Val
@@ -381,7 +400,7 @@ func (l *Loader) parseRelocs() error {
 		if overflow != 0 {
 			return fmt.Errorf("offset underflow")
 		}
-		offset, overflow = bits.Add64(offset, ph.Vaddr, 0)
+		offset, overflow = bits.Add64(offset, ph.Off, 0)
 		if overflow != 0 {
 			return fmt.Errorf("offset overflow")
 		}
@@ -552,6 +571,9 @@ func (it *tableIter[T]) getNext() (bool, error) {
 
 // lookupFromTable does a point select in a densely packed table.
 func lookupFromTable[T any](l *Loader, section *elf.Section64, i uint32, elemSize uint16) (ret T, err error) {
+	if section == nil {
+		return ret, fmt.Errorf("missing table section")
+	}
 	off := uint64(i) * uint64(elemSize)
 	if off > section.Size {
 		return ret, io.ErrUnexpectedEOF
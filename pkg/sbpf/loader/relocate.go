@@ -11,6 +11,8 @@ import (
 // relocate applies ELF relocations (for syscalls and position-independent code).
 func (l *Loader) relocate() error {
 	l.funcs = make(map[uint32]int64)
+	l.imports = make(map[uint32]string)
+	l.relocCounts = make(map[R_BPF]int)
 	if err := l.fixupRelativeCalls(); err != nil {
 		return err
 	}
@@ -31,7 +33,13 @@ func (l *Loader) fixupRelativeCalls() error {
 		off := i * sbpf.SlotSize
 		slot := sbpf.GetSlot(buf[off : off+sbpf.SlotSize])
 
-		isCall := slot.Op() == sbpf.OpCall && slot.Imm() != -1
+		var isCall bool
+		if l.version.StaticSyscalls() {
+			// Syscalls are identified by src=0
+			isCall = slot.Op() == sbpf.OpCall && slot.Src() == 1
+		} else {
+			isCall = slot.Op() == sbpf.OpCall && slot.Imm() != -1
+		}
 		if !isCall {
 			continue
 		}
@@ -45,6 +53,10 @@ func (l *Loader) fixupRelativeCalls() error {
 		if err != nil {
 			return err
 		}
+		if l.version.StaticSyscalls() {
+			// Keep relative call target
+			continue
+		}
 
 		var newImm [4]byte
 		binary.LittleEndian.PutUint32(newImm[:], hash)
@@ -78,14 +90,16 @@ func (l *Loader) applyDynamicRelocs() error {
 }
 
 func (l *Loader) applyReloc(reloc *elf.Rel64) error {
-	// TODO rOff is not checked
-	// Need to have a virtual write target here
-	rOff := reloc.Off
+	rOff := l.progOff(reloc.Off)
 	rType := R_BPF(elf.R_TYPE64(reloc.Info))
 	rSym := elf.R_SYM64(reloc.Info)
+	l.relocCounts[rType]++
 
 	switch rType {
 	case R_BPF_64_64:
+		if err := l.checkRelocOff(rOff, 2*sbpf.SlotSize); err != nil {
+			return err
+		}
 		sym, err := l.getDynsym(rSym)
 		if err != nil {
 			return err
@@ -104,6 +118,9 @@ func (l *Loader) applyReloc(reloc *elf.Rel64) error {
 		binary.LittleEndian.PutUint32(l.program[rOff+12:rOff+16], uint32(addr>>32))
 	case R_BPF_64_RELATIVE:
 		if l.textRange.contains(rOff) {
+			if err := l.checkRelocOff(rOff, 2*sbpf.SlotSize); err != nil {
+				return err
+			}
 			immLow := binary.LittleEndian.Uint32(l.program[rOff+4 : rOff+8])
 			immHi := binary.LittleEndian.Uint32(l.program[rOff+12 : rOff+16])
 
@@ -119,8 +136,11 @@ func (l *Loader) applyReloc(reloc *elf.Rel64) error {
 			binary.LittleEndian.PutUint32(l.program[rOff+4:rOff+8], uint32(addr))
 			binary.LittleEndian.PutUint32(l.program[rOff+12:rOff+16], uint32(addr>>32))
 		} else {
+			if err := l.checkRelocOff(rOff, 8); err != nil {
+				return err
+			}
 			var addr uint64
-			if l.eh.Flags == EF_SBF_V2 {
+			if l.version == sbpf.VersionV2 {
 				addr = binary.LittleEndian.Uint64(l.program[rOff : rOff+8])
 				if addr < sbpf.VaddrProgram {
 					addr += sbpf.VaddrProgram
@@ -133,6 +153,9 @@ func (l *Loader) applyReloc(reloc *elf.Rel64) error {
 			binary.LittleEndian.PutUint64(l.program[rOff:rOff+8], addr)
 		}
 	case R_BPF_64_32:
+		if err := l.checkRelocOff(rOff, sbpf.SlotSize); err != nil {
+			return err
+		}
 		sym, err := l.getDynsym(rSym)
 		if err != nil {
 			return err
@@ -145,17 +168,28 @@ func (l *Loader) applyReloc(reloc *elf.Rel64) error {
 		var hash uint32
 		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
 			// Function call
-			if !l.textRange.contains(sym.Value) {
+			symOff := l.progOff(sym.Value)
+			if !l.textRange.contains(symOff) {
 				return fmt.Errorf("out-of-bounds R_BPF_64_32 function ref")
 			}
-			target := (sym.Value - l.textRange.min) / 8
+			target := (symOff - l.textRange.min) / 8
 			hash, err = l.registerFunc(target)
 			if err != nil {
 				return fmt.Errorf("R_BPF_64_32 function ref: %w", err)
 			}
+			if l.version.StaticSyscalls() {
+				// Internal calls use relative addressing
+				if !l.textRange.contains(rOff) {
+					return fmt.Errorf("R_BPF_64_32 function ref outside of .text")
+				}
+				pc := (rOff - l.textRange.min) / 8
+				hash = uint32(target - pc - 1)
+				l.program[rOff+1] = l.program[rOff+1]&0x0f | 1<<4 // src=1
+			}
 		} else {
 			// Syscall
 			hash = sbpf.SymbolHash(name)
+			l.imports[hash] = name
 			// TODO check whether syscall is known
 		}
 
@@ -166,6 +200,14 @@ func (l *Loader) applyReloc(reloc *elf.Rel64) error {
 	return nil
 }
 
+// checkRelocOff checks that a relocation target lies within the program buffer.
+func (l *Loader) checkRelocOff(off uint64, size uint64) error {
+	if off > uint64(len(l.program)) || size > uint64(len(l.program))-off {
+		return fmt.Errorf("relocation out of bounds")
+	}
+	return nil
+}
+
 func (l *Loader) getEntrypoint() error {
 	offset := l.eh.Entry - l.shText.Addr
 	if offset%sbpf.SlotSize != 0 {
@@ -184,3 +226,18 @@ const (
 	R_BPF_64_RELATIVE R_BPF = 8
 	R_BPF_64_32       R_BPF = 10
 )
+
+func (r R_BPF) String() string {
+	switch r {
+	case R_BPF_NONE:
+		return "R_BPF_NONE"
+	case R_BPF_64_64:
+		return "R_BPF_64_64"
+	case R_BPF_64_RELATIVE:
+		return "R_BPF_64_RELATIVE"
+	case R_BPF_64_32:
+		return "R_BPF_64_32"
+	default:
+		return fmt.Sprintf("R_BPF(%d)", int(r))
+	}
+}
@@ -0,0 +1,49 @@
+package loader
+
+import (
+	"debug/elf"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// getFuncNames collects the names of functions in .text.
+//
+// Symbol names are informational only and not required for execution.
+// Thus, malformed symbol tables are skipped instead of failing the load.
+func (l *Loader) getFuncNames() {
+	l.funcNames = make(map[int64]string)
+	if l.shDynsym != nil && l.shDynstr != nil {
+		l.collectFuncNames(l.shDynsym, l.shDynstr)
+	}
+	if l.shSymtab != nil && l.shStrtab != nil {
+		l.collectFuncNames(l.shSymtab, l.shStrtab)
+	}
+}
+
+func (l *Loader) collectFuncNames(symtab *elf.Section64, strtab *elf.Section64) {
+	iter, err := l.getSymtab(symtab)
+	if err != nil {
+		return
+	}
+	for iter.Next() && iter.Err() == nil {
+		sym := iter.Item()
+		if elf.ST_TYPE(sym.Info) != elf.STT_FUNC || !l.isTextAddr(sym.Value) {
+			continue
+		}
+		pc := int64((sym.Value - l.shText.Addr) / sbpf.SlotSize)
+		if _, ok := l.funcNames[pc]; ok {
+			continue
+		}
+		name, err := l.getString(strtab, sym.Name, maxSymbolNameLen)
+		if err != nil || name == "" {
+			continue
+		}
+		l.funcNames[pc] = name
+	}
+}
+
+// isTextAddr returns whether the given ELF address points to an instruction in .text.
+func (l *Loader) isTextAddr(addr uint64) bool {
+	sh := l.shText
+	return addr >= sh.Addr && addr-sh.Addr < sh.Size && (addr-sh.Addr)%sbpf.SlotSize == 0
+}
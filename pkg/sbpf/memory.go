@@ -0,0 +1,173 @@
+package sbpf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MemoryRegion is a contiguous range of VM memory backed by a host buffer.
+type MemoryRegion struct {
+	Vaddr    uint64
+	Data     []byte
+	Writable bool
+
+	// GapSize splits the region into frames of GapSize bytes.
+	// Each frame is followed by an unmapped gap of the same size.
+	// Zero disables gaps.
+	GapSize uint64
+}
+
+// VMLen returns the size of the region in the VM address space, including gaps.
+func (r *MemoryRegion) VMLen() uint64 {
+	if r.GapSize != 0 {
+		return 2 * uint64(len(r.Data))
+	}
+	return uint64(len(r.Data))
+}
+
+// contains returns whether the VM address lies within the region (including gaps).
+func (r *MemoryRegion) contains(addr uint64) bool {
+	return addr >= r.Vaddr && addr-r.Vaddr < r.VMLen()
+}
+
+// translate returns the host memory of an access within the region.
+func (r *MemoryRegion) translate(addr uint64, size uint32, write bool) ([]byte, error) {
+	if write && !r.Writable {
+		return nil, NewExcBadAccess(addr, size, write, "write to read-only region")
+	}
+	if addr < r.Vaddr {
+		return nil, NewExcBadAccess(addr, size, write, "unmapped region")
+	}
+	off := addr - r.Vaddr
+	end := uint64(len(r.Data))
+	if r.GapSize != 0 {
+		frame, lo := off/r.GapSize, off%r.GapSize
+		if frame%2 == 1 {
+			return nil, NewExcBadAccess(addr, size, write, "access to gap")
+		}
+		off = (frame/2)*r.GapSize + lo
+		// Accesses must not run into the next gap
+		if frameEnd := off - lo + r.GapSize; frameEnd < end {
+			end = frameEnd
+		}
+	}
+	if off > end || uint64(size) > end-off {
+		return nil, NewExcBadAccess(addr, size, write, "out-of-bounds access")
+	}
+	return r.Data[off : off+uint64(size)], nil
+}
+
+// MappingMode selects how a MemoryMapping looks up regions.
+type MappingMode uint8
+
+const (
+	// MappingAligned requires each region to start at a 4 GiB boundary (Vaddr = index << 32).
+	// Lookups are a single table index.
+	MappingAligned = MappingMode(iota)
+	// MappingUnaligned allows regions at arbitrary addresses.
+	// Lookups are a binary search over the regions.
+	MappingUnaligned
+)
+
+// MemoryMapping translates VM addresses to host memory.
+type MemoryMapping struct {
+	mode    MappingMode
+	regions []MemoryRegion // sorted by Vaddr
+	table   []int          // aligned mode: region index by Vaddr>>32, -1 if unmapped
+}
+
+// NewMemoryMapping creates a memory mapping from the given regions.
+//
+// Regions must not overlap.
+func NewMemoryMapping(mode MappingMode, regions []MemoryRegion) (*MemoryMapping, error) {
+	m := new(MemoryMapping)
+	if err := m.reset(mode, regions); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// reset reinitializes the mapping with the given regions, reusing its buffers.
+func (m *MemoryMapping) reset(mode MappingMode, regions []MemoryRegion) error {
+	m.mode = mode
+	m.regions = append(m.regions[:0], regions...)
+	m.table = m.table[:0]
+	regions = m.regions
+	// Insertion sort, as there are only a few regions and sort.Slice allocates
+	for i := 1; i < len(regions); i++ {
+		for j := i; j > 0 && regions[j].Vaddr < regions[j-1].Vaddr; j-- {
+			regions[j], regions[j-1] = regions[j-1], regions[j]
+		}
+	}
+	for i := range regions {
+		r := &regions[i]
+		if r.Vaddr+r.VMLen() < r.Vaddr {
+			return fmt.Errorf("region at %#x overflows address space", r.Vaddr)
+		}
+		if i > 0 {
+			prev := &regions[i-1]
+			if prev.Vaddr+prev.VMLen() > r.Vaddr {
+				return fmt.Errorf("region at %#x overlaps with region at %#x", r.Vaddr, prev.Vaddr)
+			}
+		}
+	}
+
+	switch mode {
+	case MappingAligned:
+		for i := range regions {
+			r := &regions[i]
+			if r.Vaddr&math.MaxUint32 != 0 || r.VMLen() > 1<<32 {
+				return fmt.Errorf("region at %#x is not aligned", r.Vaddr)
+			}
+			idx := int(r.Vaddr >> 32)
+			if idx > 0xff {
+				return fmt.Errorf("region at %#x out of bounds", r.Vaddr)
+			}
+			for len(m.table) <= idx {
+				m.table = append(m.table, -1)
+			}
+			m.table[idx] = i
+		}
+	case MappingUnaligned:
+		// nothing
+	default:
+		return fmt.Errorf("invalid mapping mode %d", mode)
+	}
+	return nil
+}
+
+// Regions returns the regions of the mapping, sorted by address.
+func (m *MemoryMapping) Regions() []MemoryRegion {
+	return m.regions
+}
+
+// Region returns the region containing the given address, or nil if unmapped.
+func (m *MemoryMapping) Region(addr uint64) *MemoryRegion {
+	if m.mode == MappingAligned {
+		idx := addr >> 32
+		if idx >= uint64(len(m.table)) || m.table[idx] < 0 {
+			return nil
+		}
+		return &m.regions[m.table[idx]]
+	}
+	i := sort.Search(len(m.regions), func(i int) bool {
+		r := &m.regions[i]
+		return r.Vaddr+r.VMLen() > addr
+	})
+	if i >= len(m.regions) || !m.regions[i].contains(addr) {
+		return nil
+	}
+	return &m.regions[i]
+}
+
+// Translate returns the host memory backing an access of size bytes at addr.
+//
+// The access must not span multiple regions.
+func (m *MemoryMapping) Translate(addr uint64, size uint32, write bool) ([]byte, error) {
+	r := m.Region(addr)
+	if r == nil {
+		return nil, NewExcBadAccess(addr, size, write, "unmapped region")
+	}
+	return r.translate(addr, size, write)
+}
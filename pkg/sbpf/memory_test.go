@@ -0,0 +1,185 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMapping_Aligned(t *testing.T) {
+	ro := []byte{1, 2, 3, 4}
+	rw := make([]byte, 16)
+	m, err := NewMemoryMapping(MappingAligned, []MemoryRegion{
+		{Vaddr: VaddrInput, Data: rw, Writable: true},
+		{Vaddr: VaddrProgram, Data: ro},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, VaddrProgram, m.Regions()[0].Vaddr)
+
+	mem, err := m.Translate(VaddrProgram+1, 3, false)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2, 3, 4}, mem)
+
+	_, err = m.Translate(VaddrProgram+1, 4, false)
+	assert.Error(t, err)
+	_, err = m.Translate(VaddrProgram, 1, true)
+	assert.Error(t, err)
+	_, err = m.Translate(VaddrHeap, 1, false)
+	assert.Error(t, err)
+	_, err = m.Translate(0xff_0000_0000, 1, false)
+	assert.Error(t, err)
+
+	mem, err = m.Translate(VaddrInput+15, 1, true)
+	require.NoError(t, err)
+	mem[0] = 0xaa
+	assert.Equal(t, byte(0xaa), rw[15])
+
+	_, err = NewMemoryMapping(MappingAligned, []MemoryRegion{
+		{Vaddr: VaddrInput + 8, Data: rw},
+	})
+	assert.Error(t, err)
+}
+
+func TestMemoryMapping_Unaligned(t *testing.T) {
+	a := make([]byte, 8)
+	b := make([]byte, 8)
+	c := make([]byte, 8)
+	m, err := NewMemoryMapping(MappingUnaligned, []MemoryRegion{
+		{Vaddr: VaddrInput, Data: a, Writable: true},
+		{Vaddr: VaddrInput + 8, Data: b},
+		{Vaddr: VaddrInput + 16, Data: c, Writable: true},
+	})
+	require.NoError(t, err)
+
+	assert.Nil(t, m.Region(VaddrInput-1))
+	assert.Equal(t, VaddrInput+8, m.Region(VaddrInput+15).Vaddr)
+	assert.Equal(t, VaddrInput+16, m.Region(VaddrInput+16).Vaddr)
+	assert.Nil(t, m.Region(VaddrInput+24))
+
+	_, err = m.Translate(VaddrInput+8, 1, false)
+	assert.NoError(t, err)
+	_, err = m.Translate(VaddrInput+8, 1, true)
+	assert.Error(t, err)
+	_, err = m.Translate(VaddrInput+16, 8, true)
+	assert.NoError(t, err)
+
+	// Accesses may not span regions
+	_, err = m.Translate(VaddrInput+4, 8, false)
+	assert.Error(t, err)
+
+	_, err = NewMemoryMapping(MappingUnaligned, []MemoryRegion{
+		{Vaddr: VaddrInput, Data: a},
+		{Vaddr: VaddrInput + 4, Data: b},
+	})
+	assert.Error(t, err)
+}
+
+func TestMemoryMapping_Gaps(t *testing.T) {
+	data := make([]byte, 0x2000)
+	m, err := NewMemoryMapping(MappingAligned, []MemoryRegion{
+		{Vaddr: VaddrStack, Data: data, Writable: true, GapSize: 0x1000},
+	})
+	require.NoError(t, err)
+
+	mem, err := m.Translate(VaddrStack+0xff8, 8, true)
+	require.NoError(t, err)
+	mem[0] = 1
+	assert.Equal(t, byte(1), data[0xff8])
+
+	_, err = m.Translate(VaddrStack+0xffc, 8, false)
+	assert.Error(t, err, "access running into gap")
+	_, err = m.Translate(VaddrStack+0x1000, 1, false)
+	assert.Error(t, err, "access to gap")
+
+	mem, err = m.Translate(VaddrStack+0x2004, 4, true)
+	require.NoError(t, err)
+	mem[0] = 2
+	assert.Equal(t, byte(2), data[0x1004])
+
+	_, err = m.Translate(VaddrStack+0x3000, 1, false)
+	assert.Error(t, err)
+	_, err = m.Translate(VaddrStack+0x4000, 1, false)
+	assert.Error(t, err)
+}
+
+func TestInterpreter_StackFrames(t *testing.T) {
+	results, err := runAsm(t, `
+	entrypoint:
+		mov64 r1, 60
+		call rec
+		ldxdw r1, [r10-8]
+		call result
+		exit
+	rec:
+		stxdw [r10-8], r1
+		jeq r1, 0, done
+		sub64 r1, 1
+		call rec
+	done:
+		exit
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0}, results)
+
+	_, err = runAsm(t, `
+		stb [r10+0], 1
+		exit
+	`)
+	var exc ExcBadAccess
+	require.ErrorAs(t, err, &exc)
+	assert.Equal(t, "access to gap", exc.Reason)
+}
+
+func TestInterpreter_InputRegions(t *testing.T) {
+	program, err := Assemble(`
+		ldxb r2, [r1+0]
+		stb [r1+1], 2
+		stb [r1+8], 3
+		exit
+	`)
+	require.NoError(t, err)
+
+	meta := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+	data := make([]byte, 8)
+	run := func(writable bool) error {
+		interpreter := NewInterpreter(program, &VMOpts{
+			HeapSize: 32 * 1024,
+			Mapping:  MappingUnaligned,
+			MaxCU:    10000,
+			Syscalls: NewSyscallRegistry(),
+			InputRegions: []MemoryRegion{
+				{Vaddr: VaddrInput, Data: meta, Writable: true},
+				{Vaddr: VaddrInput + 8, Data: data, Writable: writable},
+			},
+		})
+		_, err := interpreter.Run()
+		return err
+	}
+
+	var exc ExcBadAccess
+	require.ErrorAs(t, run(false), &exc)
+	assert.Equal(t, VaddrInput+8, exc.Addr)
+	assert.True(t, exc.Write)
+
+	require.NoError(t, run(true))
+	assert.Equal(t, byte(2), meta[1])
+	assert.Equal(t, byte(3), data[0])
+
+	// Invalid mappings fail like any other exception, before the first instruction
+	res, err := NewInterpreter(program, &VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: NewSyscallRegistry(),
+		InputRegions: []MemoryRegion{
+			{Vaddr: VaddrInput, Data: meta, Writable: true},
+			{Vaddr: VaddrInput + 4, Data: data, Writable: true},
+		},
+	}).Run()
+	var mapExc *Exception
+	require.ErrorAs(t, err, &mapExc)
+	assert.Equal(t, int64(program.Entrypoint), mapExc.PC)
+	assert.EqualError(t, mapExc.Detail, "region at 0x400000004 overlaps with region at 0x400000000")
+	assert.Equal(t, uint64(0), res.Instructions)
+	assert.Equal(t, 0, res.CUUsed)
+}
@@ -8,7 +8,7 @@ const (
 	ClassStx
 	ClassAlu
 	ClassJmp
-	Class0x06 // reserved
+	ClassPqr // product/quotient/remainder (SBFv2)
 	ClassAlu64
 )
 
@@ -56,6 +56,20 @@ const (
 	AluSdiv
 )
 
+// Product/quotient/remainder operations (SBFv2)
+const (
+	PqrUhmul = uint8(0x20)
+	PqrUdiv  = uint8(0x40)
+	PqrUrem  = uint8(0x60)
+	PqrLmul  = uint8(0x80)
+	PqrShmul = uint8(0xa0)
+	PqrSdiv  = uint8(0xc0)
+	PqrSrem  = uint8(0xe0)
+
+	// Pqr64 selects 64-bit operands
+	Pqr64 = uint8(0x10)
+)
+
 // Jump operations
 const (
 	JumpAlways = uint8(iota * 0x10)
@@ -149,6 +163,31 @@ const (
 	OpSdiv64Imm = ClassAlu64 | SrcK | AluSdiv
 	OpSdiv64Reg = ClassAlu64 | SrcX | AluSdiv
 
+	OpUhmul64Imm   = ClassPqr | Pqr64 | SrcK | PqrUhmul
+	OpUhmul64Reg   = ClassPqr | Pqr64 | SrcX | PqrUhmul
+	OpUdiv32Imm    = ClassPqr | SrcK | PqrUdiv
+	OpUdiv32Reg    = ClassPqr | SrcX | PqrUdiv
+	OpUdiv64Imm    = ClassPqr | Pqr64 | SrcK | PqrUdiv
+	OpUdiv64Reg    = ClassPqr | Pqr64 | SrcX | PqrUdiv
+	OpUrem32Imm    = ClassPqr | SrcK | PqrUrem
+	OpUrem32Reg    = ClassPqr | SrcX | PqrUrem
+	OpUrem64Imm    = ClassPqr | Pqr64 | SrcK | PqrUrem
+	OpUrem64Reg    = ClassPqr | Pqr64 | SrcX | PqrUrem
+	OpLmul32Imm    = ClassPqr | SrcK | PqrLmul
+	OpLmul32Reg    = ClassPqr | SrcX | PqrLmul
+	OpLmul64Imm    = ClassPqr | Pqr64 | SrcK | PqrLmul
+	OpLmul64Reg    = ClassPqr | Pqr64 | SrcX | PqrLmul
+	OpShmul64Imm   = ClassPqr | Pqr64 | SrcK | PqrShmul
+	OpShmul64Reg   = ClassPqr | Pqr64 | SrcX | PqrShmul
+	OpPqrSdiv32Imm = ClassPqr | SrcK | PqrSdiv
+	OpPqrSdiv32Reg = ClassPqr | SrcX | PqrSdiv
+	OpPqrSdiv64Imm = ClassPqr | Pqr64 | SrcK | PqrSdiv
+	OpPqrSdiv64Reg = ClassPqr | Pqr64 | SrcX | PqrSdiv
+	OpSrem32Imm    = ClassPqr | SrcK | PqrSrem
+	OpSrem32Reg    = ClassPqr | SrcX | PqrSrem
+	OpSrem64Imm    = ClassPqr | Pqr64 | SrcK | PqrSrem
+	OpSrem64Reg    = ClassPqr | Pqr64 | SrcX | PqrSrem
+
 	OpJa      = ClassJmp | JumpAlways
 	OpJeqImm  = ClassJmp | SrcK | JumpEq
 	OpJeqReg  = ClassJmp | SrcX | JumpEq
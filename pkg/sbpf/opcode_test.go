@@ -85,6 +85,31 @@ func TestOpcodes(t *testing.T) {
 		{0xec, OpSdiv32Reg},
 		{0xef, OpSdiv64Reg},
 
+		{0x36, OpUhmul64Imm},
+		{0x3e, OpUhmul64Reg},
+		{0x46, OpUdiv32Imm},
+		{0x4e, OpUdiv32Reg},
+		{0x56, OpUdiv64Imm},
+		{0x5e, OpUdiv64Reg},
+		{0x66, OpUrem32Imm},
+		{0x6e, OpUrem32Reg},
+		{0x76, OpUrem64Imm},
+		{0x7e, OpUrem64Reg},
+		{0x86, OpLmul32Imm},
+		{0x8e, OpLmul32Reg},
+		{0x96, OpLmul64Imm},
+		{0x9e, OpLmul64Reg},
+		{0xb6, OpShmul64Imm},
+		{0xbe, OpShmul64Reg},
+		{0xc6, OpPqrSdiv32Imm},
+		{0xce, OpPqrSdiv32Reg},
+		{0xd6, OpPqrSdiv64Imm},
+		{0xde, OpPqrSdiv64Reg},
+		{0xe6, OpSrem32Imm},
+		{0xee, OpSrem32Reg},
+		{0xf6, OpSrem64Imm},
+		{0xfe, OpSrem64Reg},
+
 		{0x05, OpJa},
 		{0x15, OpJeqImm},
 		{0x1d, OpJeqReg},
@@ -0,0 +1,214 @@
+package sbpf
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Minimal encoder for the pprof protobuf format.
+//
+// Reference: https://github.com/google/pprof/blob/main/proto/profile.proto
+
+// Field numbers of the pprof messages.
+const (
+	pprofSampleType        = 1
+	pprofSample            = 2
+	pprofMapping           = 3
+	pprofLocation          = 4
+	pprofFunction          = 5
+	pprofStringTable       = 6
+	pprofTimeNanos         = 9
+	pprofPeriodType        = 11
+	pprofPeriod            = 12
+	pprofDefaultSampleType = 14
+
+	pprofValueTypeType = 1
+	pprofValueTypeUnit = 2
+
+	pprofSampleLocationID = 1
+	pprofSampleValue      = 2
+
+	pprofMappingID       = 1
+	pprofMappingStart    = 2
+	pprofMappingLimit    = 3
+	pprofMappingFilename = 5
+	pprofMappingHasFuncs = 7
+
+	pprofLocationID        = 1
+	pprofLocationMappingID = 2
+	pprofLocationAddress   = 3
+	pprofLocationLine      = 4
+
+	pprofLineFunctionID = 1
+
+	pprofFunctionID         = 1
+	pprofFunctionName       = 2
+	pprofFunctionSystemName = 3
+)
+
+// protoBuf appends protobuf wire format fields.
+type protoBuf []byte
+
+func (b *protoBuf) varint(field int, v uint64) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3)
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuf) bytes(field int, v []byte) {
+	*b = binary.AppendUvarint(*b, uint64(field)<<3|2)
+	*b = binary.AppendUvarint(*b, uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuf) packed(field int, vs []uint64) {
+	var inner []byte
+	for _, v := range vs {
+		inner = binary.AppendUvarint(inner, v)
+	}
+	b.bytes(field, inner)
+}
+
+// pprofBuilder accumulates the tables of a pprof profile.
+type pprofBuilder struct {
+	out       protoBuf
+	strings   map[string]uint64
+	locations map[pprofLocKey]uint64
+	functions map[string]uint64
+	syms      *symbolizer
+	textVA    uint64
+}
+
+// pprofLocKey identifies a location, either an instruction or a syscall leaf.
+type pprofLocKey struct {
+	pc      int64
+	syscall string
+}
+
+func (b *pprofBuilder) str(s string) uint64 {
+	if id, ok := b.strings[s]; ok {
+		return id
+	}
+	id := uint64(len(b.strings))
+	b.strings[s] = id
+	return id
+}
+
+func (b *pprofBuilder) valueType(field int, typ, unit string) {
+	var vt protoBuf
+	vt.varint(pprofValueTypeType, b.str(typ))
+	vt.varint(pprofValueTypeUnit, b.str(unit))
+	b.out.bytes(field, vt)
+}
+
+func (b *pprofBuilder) function(name string) uint64 {
+	if id, ok := b.functions[name]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[name] = id
+	var fn protoBuf
+	fn.varint(pprofFunctionID, id)
+	fn.varint(pprofFunctionName, b.str(name))
+	fn.varint(pprofFunctionSystemName, b.str(name))
+	b.out.bytes(pprofFunction, fn)
+	return id
+}
+
+func (b *pprofBuilder) location(key pprofLocKey) uint64 {
+	if id, ok := b.locations[key]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[key] = id
+
+	var fnID uint64
+	if key.syscall != "" {
+		fnID = b.function(key.syscall)
+	} else {
+		fnID = b.function(b.syms.funcName(key.pc))
+	}
+	var line protoBuf
+	line.varint(pprofLineFunctionID, fnID)
+
+	var loc protoBuf
+	loc.varint(pprofLocationID, id)
+	if key.syscall == "" {
+		loc.varint(pprofLocationMappingID, 1)
+		loc.varint(pprofLocationAddress, b.textVA+uint64(key.pc)*SlotSize)
+	}
+	loc.bytes(pprofLocationLine, line)
+	b.out.bytes(pprofLocation, loc)
+	return id
+}
+
+func (p *Profiler) encodePprof() []byte {
+	b := &pprofBuilder{
+		strings:   map[string]uint64{"": 0},
+		locations: make(map[pprofLocKey]uint64),
+		functions: make(map[string]uint64),
+		syms:      p.program.symbols(),
+		textVA:    p.program.TextVA,
+	}
+
+	b.valueType(pprofSampleType, "instructions", "count")
+	b.valueType(pprofSampleType, "compute_units", "count")
+
+	var mapping protoBuf
+	mapping.varint(pprofMappingID, 1)
+	mapping.varint(pprofMappingStart, p.program.TextVA)
+	mapping.varint(pprofMappingLimit, p.program.TextVA+uint64(len(p.program.Text)))
+	mapping.varint(pprofMappingFilename, b.str("program"))
+	mapping.varint(pprofMappingHasFuncs, 1)
+	b.out.bytes(pprofMapping, mapping)
+
+	// Emit samples in deterministic order, sorted by call stack from the root.
+	type sample struct {
+		stack []int64 // leaf first
+		c     *profCount
+	}
+	var samples []sample
+	p.walk(func(node *profNode, pc int64, c *profCount) {
+		stack := []int64{pc}
+		for n := node; n.parent != nil; n = n.parent {
+			stack = append(stack, n.callSite)
+		}
+		samples = append(samples, sample{stack, c})
+	})
+	sort.Slice(samples, func(i, j int) bool {
+		a, b := samples[i].stack, samples[j].stack
+		for k := 1; k <= len(a) && k <= len(b); k++ {
+			if x, y := a[len(a)-k], b[len(b)-k]; x != y {
+				return x < y
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	for _, s := range samples {
+		var locs []uint64
+		if name, ok := b.syms.syscallAt(s.stack[0], p.Syscalls); ok {
+			locs = append(locs, b.location(pprofLocKey{syscall: name}))
+		}
+		for _, pc := range s.stack {
+			locs = append(locs, b.location(pprofLocKey{pc: pc}))
+		}
+		var smpl protoBuf
+		smpl.packed(pprofSampleLocationID, locs)
+		smpl.packed(pprofSampleValue, []uint64{uint64(s.c.ins), uint64(s.c.cu)})
+		b.out.bytes(pprofSample, smpl)
+	}
+
+	b.out.varint(pprofTimeNanos, uint64(p.start.UnixNano()))
+	b.valueType(pprofPeriodType, "instructions", "count")
+	b.out.varint(pprofPeriod, 1)
+	b.out.varint(pprofDefaultSampleType, b.str("compute_units"))
+
+	table := make([]string, len(b.strings))
+	for s, id := range b.strings {
+		table[id] = s
+	}
+	for _, s := range table {
+		b.out.bytes(pprofStringTable, []byte(s))
+	}
+	return b.out
+}
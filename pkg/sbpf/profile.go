@@ -0,0 +1,205 @@
+package sbpf
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Profiler counts executed instructions and consumed compute units by call stack.
+//
+// Call stacks are derived from the shadow stack,
+// so they are accurate regardless of how the program uses its stack memory.
+// A Profiler may be shared by consecutive runs of the same program, but not by concurrent ones.
+type Profiler struct {
+	// Syscalls optionally resolves the names of invoked syscalls in the pprof output.
+	Syscalls SyscallRegistry
+
+	program *Program
+	root    *profNode
+	path    []*profNode // nodes of the current shadow stack
+	cur     *profCount
+	cuStart int
+	start   time.Time
+}
+
+// profNode is a call stack in the call tree, identified by its call sites.
+type profNode struct {
+	callSite int64 // PC of the call instruction in the parent
+	parent   *profNode
+	children map[int64]*profNode
+	samples  map[int64]*profCount
+}
+
+type profCount struct {
+	ins int64
+	cu  int64
+}
+
+// NewProfiler creates an empty profile for the given program.
+func NewProfiler(p *Program) *Profiler {
+	root := newProfNode(nil, 0)
+	return &Profiler{
+		program: p,
+		root:    root,
+		path:    []*profNode{root},
+		start:   time.Now(),
+	}
+}
+
+func newProfNode(parent *profNode, callSite int64) *profNode {
+	return &profNode{
+		callSite: callSite,
+		parent:   parent,
+		children: make(map[int64]*profNode),
+		samples:  make(map[int64]*profCount),
+	}
+}
+
+// begin is called before an instruction executes.
+func (p *Profiler) begin(pc int64, frames []Frame, cuLeft int) {
+	depth := len(frames) // root frame has no call site
+	if len(p.path) > depth {
+		p.path = p.path[:depth]
+	}
+	for len(p.path) < depth {
+		parent := p.path[len(p.path)-1]
+		callSite := frames[len(p.path)].RetAddr - 1
+		node, ok := parent.children[callSite]
+		if !ok {
+			node = newProfNode(parent, callSite)
+			parent.children[callSite] = node
+		}
+		p.path = append(p.path, node)
+	}
+	node := p.path[len(p.path)-1]
+	count, ok := node.samples[pc]
+	if !ok {
+		count = new(profCount)
+		node.samples[pc] = count
+	}
+	p.cur = count
+	p.cuStart = cuLeft
+}
+
+// end is called after an instruction executed.
+func (p *Profiler) end(cuLeft int) {
+	p.cur.ins++
+	p.cur.cu += int64(p.cuStart - cuLeft)
+}
+
+// Total returns the number of instructions and compute units recorded.
+func (p *Profiler) Total() (ins int64, cu int64) {
+	p.walk(func(_ *profNode, _ int64, c *profCount) {
+		ins += c.ins
+		cu += c.cu
+	})
+	return
+}
+
+// FuncStats returns the compute units consumed by each function, excluding callees.
+//
+// Functions are identified by name, see FuncName.
+func (p *Profiler) FuncStats() map[string]int64 {
+	stats := make(map[string]int64)
+	syms := p.program.symbols()
+	p.walk(func(_ *profNode, pc int64, c *profCount) {
+		stats[syms.funcName(pc)] += c.cu
+	})
+	return stats
+}
+
+func (p *Profiler) walk(fn func(node *profNode, pc int64, c *profCount)) {
+	var visit func(node *profNode)
+	visit = func(node *profNode) {
+		for pc, c := range node.samples {
+			fn(node, pc, c)
+		}
+		for _, child := range node.children {
+			visit(child)
+		}
+	}
+	visit(p.root)
+}
+
+// WritePprof writes the profile in gzip-compressed pprof format.
+//
+// The profile has two sample types, "instructions" and "compute_units" (the default).
+// Locations are virtual addresses of the program text.
+// Syscall invocations appear as separate leaf functions.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(p.encodePprof()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// symbolizer maps PCs to the functions containing them.
+type symbolizer struct {
+	program *Program
+	starts  []int64 // sorted function start PCs
+}
+
+func newSymbolizer(p *Program) *symbolizer {
+	set := map[int64]struct{}{int64(p.Entrypoint): {}}
+	for _, pc := range p.Funcs {
+		set[pc] = struct{}{}
+	}
+	for pc := range p.FuncNames {
+		set[pc] = struct{}{}
+	}
+	starts := make([]int64, 0, len(set))
+	for pc := range set {
+		starts = append(starts, pc)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	return &symbolizer{program: p, starts: starts}
+}
+
+// funcStart returns the start PC of the function containing pc.
+func (s *symbolizer) funcStart(pc int64) int64 {
+	i := sort.Search(len(s.starts), func(i int) bool { return s.starts[i] > pc })
+	if i == 0 {
+		return 0
+	}
+	return s.starts[i-1]
+}
+
+// funcName returns the name of the function containing pc.
+func (s *symbolizer) funcName(pc int64) string {
+	return FuncName(s.program, s.funcStart(pc))
+}
+
+// FuncName returns the symbol name of the function starting at the given PC.
+//
+// Falls back to "entrypoint" and "function_<pc>" for unnamed functions.
+func FuncName(p *Program, pc int64) string {
+	if name, ok := p.FuncNames[pc]; ok {
+		return name
+	}
+	if pc == int64(p.Entrypoint) {
+		return "entrypoint"
+	}
+	return fmt.Sprintf("function_%d", pc)
+}
+
+// syscallAt returns the name of the syscall invoked by the instruction at pc, if any.
+func (s *symbolizer) syscallAt(pc int64, syscalls SyscallRegistry) (string, bool) {
+	if pc < 0 || (pc+1)*SlotSize > int64(len(s.program.Text)) {
+		return "", false
+	}
+	ins := GetSlot(s.program.Text[pc*SlotSize:])
+	if ins.Op() != OpCall {
+		return "", false
+	}
+	if _, ok := s.program.Funcs[ins.Uimm()]; ok {
+		return "", false
+	}
+	if name, ok := syscalls.Name(ins.Uimm()); ok {
+		return name, true
+	}
+	return fmt.Sprintf("syscall_%#08x", ins.Uimm()), true
+}
@@ -0,0 +1,237 @@
+package sbpf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiler(t *testing.T) {
+	program, err := Assemble(`
+	work:
+		mov64 r2, 4
+	work_loop:
+		call expensive
+		sub64 r2, 1
+		jne r2, 0, work_loop
+		exit
+
+	entrypoint:
+		call work
+		call expensive
+		exit
+	`)
+	require.NoError(t, err)
+	program.FuncNames = map[int64]string{0: "work"}
+
+	syscalls := NewSyscallRegistry()
+	syscalls.Register("expensive", SyscallFunc0(func(_ VM, cuIn int) (uint64, int, error) {
+		return 0, cuIn - 100, nil
+	}))
+	require.NoError(t, program.Verify(syscalls))
+
+	prof := NewProfiler(program)
+	prof.Syscalls = syscalls
+	interpreter := NewInterpreter(program, &VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    10000,
+		Syscalls: syscalls,
+		Profiler: prof,
+	})
+	res, err := interpreter.Run()
+	require.NoError(t, err)
+
+	ins, cu := prof.Total()
+	assert.Equal(t, int64(3+1+4*3+1), ins)
+	assert.Equal(t, int64(500), cu)
+	assert.Equal(t, uint64(ins), res.Instructions)
+	assert.Equal(t, int(cu), res.CUUsed)
+	assert.Equal(t, map[string]int64{
+		"work":       400,
+		"entrypoint": 100,
+	}, prof.FuncStats())
+
+	var buf bytes.Buffer
+	require.NoError(t, prof.WritePprof(&buf))
+	p := decodePprof(t, &buf)
+	assert.Equal(t, []string{"instructions/count", "compute_units/count"}, p.sampleTypes)
+	// Leaf function first, values are instructions and compute units.
+	// Syscalls are charged to the calling instruction.
+	assert.Equal(t, map[string][2]uint64{
+		"entrypoint":                {2, 0},
+		"expensive;entrypoint":      {1, 100},
+		"work;entrypoint":           {10, 0},
+		"expensive;work;entrypoint": {4, 400},
+	}, p.stacks)
+	assert.Len(t, p.addrs, 8) // every executed instruction
+	for addr, fn := range p.addrs {
+		pc := int64(addr-program.TextVA) / SlotSize
+		assert.Equal(t, program.Symbolize(pc).Func, fn, "address %#x", addr)
+	}
+}
+
+// pprofProfile is the content of a pprof profile relevant to tests.
+type pprofProfile struct {
+	sampleTypes []string             // "type/unit"
+	stacks      map[string][2]uint64 // summed sample values by function names, leaf first
+	addrs       map[uint64]string    // function names by instruction address
+}
+
+// decodePprof decodes a gzipped pprof profile with two sample values.
+func decodePprof(t *testing.T, r io.Reader) *pprofProfile {
+	zr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var strs []string
+	var sampleTypes, samples, locations, functions [][]byte
+	for _, f := range decodeProto(t, raw) {
+		switch f.num {
+		case pprofSampleType:
+			sampleTypes = append(sampleTypes, f.bytes)
+		case pprofSample:
+			samples = append(samples, f.bytes)
+		case pprofLocation:
+			locations = append(locations, f.bytes)
+		case pprofFunction:
+			functions = append(functions, f.bytes)
+		case pprofStringTable:
+			strs = append(strs, string(f.bytes))
+		}
+	}
+	str := func(id uint64) string {
+		require.Less(t, id, uint64(len(strs)))
+		return strs[id]
+	}
+
+	p := &pprofProfile{
+		stacks: make(map[string][2]uint64),
+		addrs:  make(map[uint64]string),
+	}
+	for _, b := range sampleTypes {
+		var typ, unit uint64
+		for _, f := range decodeProto(t, b) {
+			switch f.num {
+			case pprofValueTypeType:
+				typ = f.varint
+			case pprofValueTypeUnit:
+				unit = f.varint
+			}
+		}
+		p.sampleTypes = append(p.sampleTypes, str(typ)+"/"+str(unit))
+	}
+	funcNames := make(map[uint64]string)
+	for _, b := range functions {
+		var id, name uint64
+		for _, f := range decodeProto(t, b) {
+			switch f.num {
+			case pprofFunctionID:
+				id = f.varint
+			case pprofFunctionName:
+				name = f.varint
+			}
+		}
+		funcNames[id] = str(name)
+	}
+	locNames := make(map[uint64]string)
+	for _, b := range locations {
+		var id, addr uint64
+		var name string
+		for _, f := range decodeProto(t, b) {
+			switch f.num {
+			case pprofLocationID:
+				id = f.varint
+			case pprofLocationAddress:
+				addr = f.varint
+			case pprofLocationLine:
+				for _, lf := range decodeProto(t, f.bytes) {
+					if lf.num == pprofLineFunctionID {
+						name = funcNames[lf.varint]
+					}
+				}
+			}
+		}
+		require.NotEmpty(t, name, "location %d", id)
+		locNames[id] = name
+		if addr != 0 {
+			p.addrs[addr] = name
+		}
+	}
+	for _, b := range samples {
+		var names []string
+		var values []uint64
+		for _, f := range decodeProto(t, b) {
+			switch f.num {
+			case pprofSampleLocationID:
+				for _, id := range decodePacked(t, f.bytes) {
+					name, ok := locNames[id]
+					require.True(t, ok, "location %d", id)
+					// Collapse instructions of the same function
+					if len(names) == 0 || names[len(names)-1] != name {
+						names = append(names, name)
+					}
+				}
+			case pprofSampleValue:
+				values = decodePacked(t, f.bytes)
+			}
+		}
+		require.Len(t, values, 2)
+		key := strings.Join(names, ";")
+		sum := p.stacks[key]
+		p.stacks[key] = [2]uint64{sum[0] + values[0], sum[1] + values[1]}
+	}
+	return p
+}
+
+// protoField is a varint or length-delimited protobuf field.
+type protoField struct {
+	num    int
+	varint uint64
+	bytes  []byte
+}
+
+// decodeProto splits a protobuf message into its fields.
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		require.Greater(t, n, 0)
+		b = b[n:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.varint, n = binary.Uvarint(b)
+			require.Greater(t, n, 0)
+			b = b[n:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			require.Greater(t, n, 0)
+			require.LessOrEqual(t, size, uint64(len(b)-n))
+			f.bytes = b[n : n+int(size)]
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// decodePacked decodes packed repeated varints.
+func decodePacked(t *testing.T, b []byte) []uint64 {
+	var vs []uint64
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		require.Greater(t, n, 0)
+		vs = append(vs, v)
+		b = b[n:]
+	}
+	return vs
+}
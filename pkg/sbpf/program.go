@@ -1,15 +1,35 @@
 package sbpf
 
+import "sync"
+
 // Program is a loaded SBF program.
 type Program struct {
+	Version    Version
 	RO         []byte // read-only segment containing text and ELFs
 	Text       []byte
 	TextVA     uint64
 	Entrypoint uint64 // PC
 	Funcs      map[uint32]int64
+	FuncNames  map[int64]string // optional, function names by PC
+	Lines      *LineTable       // optional, source locations by PC
+
+	symsOnce sync.Once
+	syms     *symbolizer
+}
+
+// symbols returns the symbolizer of the program, created on first use.
+//
+// Function names may not change afterwards.
+func (p *Program) symbols() *symbolizer {
+	p.symsOnce.Do(func() { p.syms = newSymbolizer(p) })
+	return p.syms
 }
 
 // Verify runs the static bytecode verifier.
-func (p *Program) Verify() error {
-	return NewVerifier(p).Verify()
+//
+// Calls must target a function of the program or a syscall in the registry.
+func (p *Program) Verify(syscalls SyscallRegistry) error {
+	v := NewVerifier(p)
+	v.Syscalls = syscalls
+	return v.Verify()
 }
@@ -24,6 +24,11 @@ func IsLongIns(op uint8) bool {
 	return op == OpLddw
 }
 
+// IsJump returns whether the opcode is a conditional or unconditional jump with an offset.
+func IsJump(op uint8) bool {
+	return op&0x07 == ClassJmp && op != OpCall && op != OpCallx && op != OpExit
+}
+
 // Slot holds the content of one instruction slot.
 type Slot uint64
 
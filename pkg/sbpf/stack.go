@@ -12,6 +12,7 @@ package sbpf
 //
 // New frames get allocated upwards.
 // Each frame is followed by a gap of size StackFrameSize.
+// Gaps are not backed by memory, accessing them raises an exception (see MemoryRegion.GapSize).
 //
 //	[0x1_0000_0000]: Frame
 //	[0x1_0000_1000]: Gap
@@ -19,19 +20,27 @@ package sbpf
 //	[0x1_0000_3000]: Gap
 //	...
 //
+// # Dynamic stack frames
+//
+// Since SBFv2, the memory stack is one contiguous region without gaps.
+// The frame pointer starts at the highest address of the stack.
+// Programs allocate stack space by adjusting the frame pointer themselves (add64 r10, imm).
+// Calls preserve the frame pointer and returns restore it.
+//
 // # Shadow stack
 //
 // The shadow stack is not directly accessible from SBF.
 // It stores return addresses and caller-preserved registers.
 type Stack struct {
-	mem    []byte
-	sp     uint64
-	shadow []Frame
+	mem     []byte
+	sp      uint64
+	shadow  []Frame
+	dynamic bool
 }
 
 // Frame is an entry on the shadow stack.
 type Frame struct {
-	FramePtr uint64
+	FramePtr uint64 // frame pointer at function entry
 	NVRegs   [4]uint64
 	RetAddr  int64
 }
@@ -47,13 +56,34 @@ const StackDepth = 64
 func NewStack() Stack {
 	s := Stack{
 		mem:    make([]byte, StackDepth*StackFrameSize),
-		sp:     VaddrStack,
 		shadow: make([]Frame, 1, StackDepth),
 	}
+	s.reset(false)
+	return s
+}
+
+// NewDynamicStack creates a stack with dynamic frames (SBFv2).
+func NewDynamicStack() Stack {
+	s := Stack{
+		mem:    make([]byte, StackDepth*StackFrameSize),
+		shadow: make([]Frame, 1, StackDepth),
+	}
+	s.reset(true)
+	return s
+}
+
+// reset unwinds all call frames.
+// Does not clear the stack memory.
+func (s *Stack) reset(dynamic bool) {
+	s.sp = VaddrStack
+	s.dynamic = dynamic
+	s.shadow = s.shadow[:1]
 	s.shadow[0] = Frame{
 		FramePtr: VaddrStack + StackFrameSize,
 	}
-	return s
+	if dynamic {
+		s.shadow[0].FramePtr = VaddrStack + uint64(len(s.mem))
+	}
 }
 
 // GetFramePtr returns the current frame pointer.
@@ -61,39 +91,28 @@ func (s *Stack) GetFramePtr() uint64 {
 	return s.shadow[len(s.shadow)-1].FramePtr
 }
 
-// GetFrame returns the stack frame memory slice containing the frame pointer.
-//
-// The returned slice starts at the location within the frame as indicated by the address.
-// To get the full frame, align the provided address by StackFrameSize.
-//
-// Returns nil if the program tries to address a gap or out-of-bounds memory.
-func (s *Stack) GetFrame(addr uint32) []byte {
-	hi, lo := addr/StackFrameSize, addr%StackFrameSize
-	if hi > StackDepth || hi%2 == 1 {
-		return nil
-	}
-	pos := hi / 2
-	off := pos * StackFrameSize
-	return s.mem[off+lo : off+StackFrameSize]
-}
-
 // Push allocates a new call frame.
 //
 // Saves the given nonvolatile regs and return address.
+// fp is the caller's frame pointer, which is kept as is with dynamic frames.
 // Returns the new frame pointer.
-func (s *Stack) Push(nvRegs *[4]uint64, ret int64) (fp uint64, ok bool) {
+func (s *Stack) Push(nvRegs *[4]uint64, fp uint64, ret int64) (newFp uint64, ok bool) {
 	if ok = len(s.shadow) < cap(s.shadow); !ok {
 		return
 	}
 
-	fp = s.GetFramePtr() + 2*StackFrameSize
+	if s.dynamic {
+		newFp = fp
+	} else {
+		newFp = s.GetFramePtr() + 2*StackFrameSize
+		s.sp = newFp - StackFrameSize
+	}
 	s.shadow = s.shadow[:len(s.shadow)+1]
 	s.shadow[len(s.shadow)-1] = Frame{
-		FramePtr: fp,
+		FramePtr: newFp,
 		NVRegs:   *nvRegs,
 		RetAddr:  ret,
 	}
-	s.sp = fp - StackFrameSize
 	return
 }
 
@@ -111,7 +130,11 @@ func (s *Stack) Pop(nvRegs *[4]uint64) (fp uint64, ret int64, ok bool) {
 	var frame Frame
 	frame, s.shadow = s.shadow[len(s.shadow)-1], s.shadow[:len(s.shadow)-1]
 
-	fp = s.GetFramePtr()
+	if s.dynamic {
+		fp = frame.FramePtr
+	} else {
+		fp = s.GetFramePtr()
+	}
 	*nvRegs = frame.NVRegs
 	ret = frame.RetAddr
 	ok = true
@@ -2,6 +2,7 @@ package sbpf
 
 import (
 	"encoding/binary"
+	"sort"
 
 	"github.com/spaolacci/murmur3"
 )
@@ -31,22 +32,54 @@ type Syscall interface {
 	Invoke(vm VM, r1, r2, r3, r4, r5 uint64, cuIn int) (r0 uint64, cuOut int, err error)
 }
 
-type SyscallRegistry map[uint32]Syscall
+// SyscallRegistry maps syscall hashes to their implementations and names.
+//
+// The zero value is an empty registry that cannot be registered to.
+type SyscallRegistry struct {
+	syscalls map[uint32]Syscall
+	names    map[uint32]string
+}
 
 func NewSyscallRegistry() SyscallRegistry {
-	return make(SyscallRegistry)
+	return SyscallRegistry{
+		syscalls: make(map[uint32]Syscall),
+		names:    make(map[uint32]string),
+	}
 }
 
 func (s SyscallRegistry) Register(name string, syscall Syscall) (hash uint32, ok bool) {
 	hash = SymbolHash(name)
-	if _, exist := s[hash]; exist {
+	if _, exist := s.syscalls[hash]; exist {
 		return 0, false // collision or duplicate
 	}
-	s[hash] = syscall
+	s.syscalls[hash] = syscall
+	s.names[hash] = name
 	ok = true
 	return
 }
 
+// Lookup returns the syscall with the given hash.
+func (s SyscallRegistry) Lookup(hash uint32) (syscall Syscall, ok bool) {
+	syscall, ok = s.syscalls[hash]
+	return
+}
+
+// Name returns the name of the syscall with the given hash.
+func (s SyscallRegistry) Name(hash uint32) (name string, ok bool) {
+	name, ok = s.names[hash]
+	return
+}
+
+// Hashes returns the hashes of all registered syscalls in ascending order.
+func (s SyscallRegistry) Hashes() []uint32 {
+	hashes := make([]uint32, 0, len(s.syscalls))
+	for hash := range s.syscalls {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
 // Convenience Methods
 
 type SyscallFunc0 func(vm VM, cuIn int) (r0 uint64, cuOut int, err error)
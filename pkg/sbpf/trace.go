@@ -0,0 +1,72 @@
+package sbpf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tracer receives a record of every executed instruction.
+type Tracer interface {
+	// TraceIns is called after an instruction executed.
+	//
+	// The event and its slices are reused by the interpreter,
+	// and are only valid for the duration of the call.
+	TraceIns(ev *TraceEvent)
+}
+
+// TracerFunc adapts a function to the Tracer interface.
+type TracerFunc func(ev *TraceEvent)
+
+func (f TracerFunc) TraceIns(ev *TraceEvent) {
+	f(ev)
+}
+
+// TraceEvent describes the execution of one instruction.
+type TraceEvent struct {
+	Step     uint64 // number of instructions executed before
+	PC       int64
+	Slot     Slot // raw instruction
+	Slot2    Slot // second slot of long instructions, zero otherwise
+	Before   [11]uint64
+	After    [11]uint64
+	CUBefore int
+	CUAfter  int
+	Syscall  bool // instruction invoked a syscall
+	Mem      []MemAccess
+	Err      error // exception raised by the instruction, if any
+}
+
+// MemAccess is a memory access made by an instruction or syscall.
+type MemAccess struct {
+	Addr  uint64
+	Size  uint32
+	Write bool
+	Value uint64 // value loaded or stored for accesses up to 8 bytes
+}
+
+// String returns a human-readable line describing the event.
+func (e *TraceEvent) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "% 5d [", e.Step)
+	for i, reg := range e.Before {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%016x", reg)
+	}
+	fmt.Fprintf(&b, "] % 5d: %s", e.PC, Disassemble(e.Slot, e.Slot2))
+	for _, m := range e.Mem {
+		if m.Write {
+			fmt.Fprintf(&b, " W[%#x;%d]", m.Addr, m.Size)
+		} else {
+			fmt.Fprintf(&b, " R[%#x;%d]", m.Addr, m.Size)
+		}
+		if m.Size <= 8 {
+			fmt.Fprintf(&b, "=%#x", m.Value)
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, " !%s", e.Err)
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// Divergence describes the first difference between two traces.
+type Divergence struct {
+	Step   uint64
+	A, B   *sbpf.TraceEvent // nil if the respective trace ended early
+	Reason string
+}
+
+func (d *Divergence) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "traces diverge at step %d: %s", d.Step, d.Reason)
+	if d.A != nil {
+		fmt.Fprintf(&b, "\n  a: %s", d.A)
+	}
+	if d.B != nil {
+		fmt.Fprintf(&b, "\n  b: %s", d.B)
+	}
+	return b.String()
+}
+
+// Diff reads two traces in lockstep and returns the first divergence.
+//
+// Returns nil if the traces are equivalent.
+// CU values are only compared if compareCU is set,
+// since other VMs may not implement identical metering.
+func Diff(a, b *Reader, compareCU bool) (*Divergence, error) {
+	var evA, evB sbpf.TraceEvent
+	for {
+		errA := a.Next(&evA)
+		if errA != nil && !errors.Is(errA, io.EOF) {
+			return nil, fmt.Errorf("trace a: %w", errA)
+		}
+		errB := b.Next(&evB)
+		if errB != nil && !errors.Is(errB, io.EOF) {
+			return nil, fmt.Errorf("trace b: %w", errB)
+		}
+		switch {
+		case errA != nil && errB != nil:
+			return nil, nil
+		case errA != nil:
+			return &Divergence{Step: evB.Step, B: &evB, Reason: "trace a ended"}, nil
+		case errB != nil:
+			return &Divergence{Step: evA.Step, A: &evA, Reason: "trace b ended"}, nil
+		}
+		if reason := compare(&evA, &evB, compareCU); reason != "" {
+			return &Divergence{Step: evA.Step, A: &evA, B: &evB, Reason: reason}, nil
+		}
+	}
+}
+
+// compare returns a description of the first difference between two events.
+func compare(a, b *sbpf.TraceEvent, compareCU bool) string {
+	switch {
+	case a.PC != b.PC:
+		return fmt.Sprintf("pc %d != %d", a.PC, b.PC)
+	case a.Slot != b.Slot || a.Slot2 != b.Slot2:
+		return "instruction mismatch"
+	}
+	for i := range a.Before {
+		if a.Before[i] != b.Before[i] {
+			return fmt.Sprintf("r%d before %#x != %#x", i, a.Before[i], b.Before[i])
+		}
+	}
+	for i := range a.After {
+		if a.After[i] != b.After[i] {
+			return fmt.Sprintf("r%d after %#x != %#x", i, a.After[i], b.After[i])
+		}
+	}
+	if len(a.Mem) != len(b.Mem) {
+		return fmt.Sprintf("%d memory accesses != %d", len(a.Mem), len(b.Mem))
+	}
+	for i := range a.Mem {
+		if a.Mem[i] != b.Mem[i] {
+			return fmt.Sprintf("memory access %d: %+v != %+v", i, a.Mem[i], b.Mem[i])
+		}
+	}
+	if (a.Err == nil) != (b.Err == nil) {
+		return fmt.Sprintf("error %v != %v", a.Err, b.Err)
+	}
+	if compareCU && (a.CUBefore != b.CUBefore || a.CUAfter != b.CUAfter) {
+		return fmt.Sprintf("cu %d->%d != %d->%d", a.CUBefore, a.CUAfter, b.CUBefore, b.CUAfter)
+	}
+	return ""
+}
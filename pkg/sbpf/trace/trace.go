@@ -0,0 +1,351 @@
+// Package trace implements a compact binary format for SBF execution traces.
+//
+// A trace file starts with an 8 byte magic "SBFTRACE" and a little-endian uint32 version,
+// followed by one record per executed instruction.
+// Records are delta-encoded against the previous record,
+// so that traces of long executions stay small.
+//
+// Record layout (all integers varint-encoded unless stated otherwise):
+//
+//	flags     uvarint (flagSlot2, flagSyscall, flagErr)
+//	step      uvarint, delta to previous step minus one
+//	pc        varint, delta to previous pc
+//	slot      uint64 little-endian, raw instruction
+//	slot2     uint64 little-endian, present if flagSlot2
+//	cuBefore  varint, delta to previous CU after
+//	cuCost    varint, CU before minus CU after
+//	before    register set, delta to previous registers after
+//	after     register set, delta to registers before
+//	mem       uvarint count, then per access: addr, size, write (byte), value if size <= 8
+//	err       uvarint length and message, present if flagErr
+//
+// A register set is a uvarint bit mask of changed registers followed by their new values as uvarints.
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// Magic is the file header of binary traces.
+const Magic = "SBFTRACE"
+
+// Version is the current format version.
+const Version = 1
+
+const (
+	flagSlot2 = 1 << iota
+	flagSyscall
+	flagErr
+)
+
+// maxErrLen limits the size of error messages when decoding.
+const maxErrLen = 1 << 16
+
+// maxMemAccesses limits the number of memory accesses per record when decoding.
+const maxMemAccesses = 1 << 16
+
+// state is the delta encoding context shared by Writer and Reader.
+type state struct {
+	step   uint64
+	pc     int64
+	cu     int
+	regs   [11]uint64
+	primed bool
+}
+
+// Writer encodes trace events to a stream.
+//
+// Writer implements sbpf.Tracer and can be passed to sbpf.VMOpts directly.
+// Since tracing cannot fail execution, write errors are deferred until Flush.
+type Writer struct {
+	wr  *bufio.Writer
+	st  state
+	buf []byte
+	err error
+}
+
+// NewWriter writes the trace header and returns a writer.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	var hdr [12]byte
+	copy(hdr[:8], Magic)
+	binary.LittleEndian.PutUint32(hdr[8:], Version)
+	if _, err := bw.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &Writer{wr: bw}, nil
+}
+
+// TraceIns implements sbpf.Tracer.
+func (w *Writer) TraceIns(ev *sbpf.TraceEvent) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.Write(ev)
+}
+
+// Write appends one event to the trace.
+func (w *Writer) Write(ev *sbpf.TraceEvent) error {
+	var flags uint64
+	if ev.Slot2 != 0 {
+		flags |= flagSlot2
+	}
+	if ev.Syscall {
+		flags |= flagSyscall
+	}
+	if ev.Err != nil {
+		flags |= flagErr
+	}
+
+	b := w.buf[:0]
+	b = binary.AppendUvarint(b, flags)
+	stepDelta := ev.Step
+	if w.st.primed {
+		stepDelta = ev.Step - w.st.step - 1
+	}
+	b = binary.AppendUvarint(b, stepDelta)
+	b = binary.AppendVarint(b, ev.PC-w.st.pc)
+	b = binary.LittleEndian.AppendUint64(b, uint64(ev.Slot))
+	if flags&flagSlot2 != 0 {
+		b = binary.LittleEndian.AppendUint64(b, uint64(ev.Slot2))
+	}
+	b = binary.AppendVarint(b, int64(ev.CUBefore-w.st.cu))
+	b = binary.AppendVarint(b, int64(ev.CUBefore-ev.CUAfter))
+	b = appendRegs(b, &w.st.regs, &ev.Before)
+	b = appendRegs(b, &ev.Before, &ev.After)
+	b = binary.AppendUvarint(b, uint64(len(ev.Mem)))
+	for _, m := range ev.Mem {
+		b = binary.AppendUvarint(b, m.Addr)
+		b = binary.AppendUvarint(b, uint64(m.Size))
+		if m.Write {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		if m.Size <= 8 {
+			b = binary.AppendUvarint(b, m.Value)
+		}
+	}
+	if flags&flagErr != 0 {
+		msg := ev.Err.Error()
+		if len(msg) > maxErrLen {
+			msg = msg[:maxErrLen]
+		}
+		b = binary.AppendUvarint(b, uint64(len(msg)))
+		b = append(b, msg...)
+	}
+	w.buf = b
+
+	w.st = state{
+		step:   ev.Step,
+		pc:     ev.PC,
+		cu:     ev.CUAfter,
+		regs:   ev.After,
+		primed: true,
+	}
+	_, err := w.wr.Write(b)
+	return err
+}
+
+// Flush writes buffered data to the underlying writer
+// and returns the first error that occurred while tracing.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.wr.Flush()
+}
+
+func appendRegs(b []byte, prev *[11]uint64, regs *[11]uint64) []byte {
+	var mask uint64
+	for i := range regs {
+		if regs[i] != prev[i] {
+			mask |= 1 << i
+		}
+	}
+	b = binary.AppendUvarint(b, mask)
+	for i := range regs {
+		if mask&(1<<i) != 0 {
+			b = binary.AppendUvarint(b, regs[i])
+		}
+	}
+	return b
+}
+
+// Reader decodes trace events from a stream.
+type Reader struct {
+	rd *bufio.Reader
+	st state
+}
+
+// Error is an exception message restored from a trace.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// NewReader reads the trace header and returns a reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var hdr [12]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read trace header: %w", err)
+	}
+	if string(hdr[:8]) != Magic {
+		return nil, fmt.Errorf("not a trace file")
+	}
+	if v := binary.LittleEndian.Uint32(hdr[8:]); v != Version {
+		return nil, fmt.Errorf("unsupported trace version %d", v)
+	}
+	return &Reader{rd: br}, nil
+}
+
+// Next decodes the next event into ev, reusing its Mem slice.
+//
+// Returns io.EOF at the end of the trace.
+// Decoded exceptions are of type Error.
+func (r *Reader) Next(ev *sbpf.TraceEvent) error {
+	flags, err := binary.ReadUvarint(r.rd)
+	if err != nil {
+		return err // io.EOF on clean end
+	}
+	if err := r.next(ev, flags); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("corrupt trace record: %w", err)
+	}
+	return nil
+}
+
+func (r *Reader) next(ev *sbpf.TraceEvent, flags uint64) error {
+	stepDelta, err := binary.ReadUvarint(r.rd)
+	if err != nil {
+		return err
+	}
+	ev.Step = stepDelta
+	if r.st.primed {
+		ev.Step = r.st.step + 1 + stepDelta
+	}
+	pcDelta, err := binary.ReadVarint(r.rd)
+	if err != nil {
+		return err
+	}
+	ev.PC = r.st.pc + pcDelta
+	if ev.Slot, err = r.readSlot(); err != nil {
+		return err
+	}
+	ev.Slot2 = 0
+	if flags&flagSlot2 != 0 {
+		if ev.Slot2, err = r.readSlot(); err != nil {
+			return err
+		}
+	}
+	ev.Syscall = flags&flagSyscall != 0
+	cuDelta, err := binary.ReadVarint(r.rd)
+	if err != nil {
+		return err
+	}
+	ev.CUBefore = r.st.cu + int(cuDelta)
+	cuCost, err := binary.ReadVarint(r.rd)
+	if err != nil {
+		return err
+	}
+	ev.CUAfter = ev.CUBefore - int(cuCost)
+	if err := r.readRegs(&ev.Before, &r.st.regs); err != nil {
+		return err
+	}
+	if err := r.readRegs(&ev.After, &ev.Before); err != nil {
+		return err
+	}
+
+	n, err := binary.ReadUvarint(r.rd)
+	if err != nil {
+		return err
+	}
+	if n > maxMemAccesses {
+		return fmt.Errorf("too many memory accesses (%d)", n)
+	}
+	ev.Mem = ev.Mem[:0]
+	for i := uint64(0); i < n; i++ {
+		var m sbpf.MemAccess
+		if m.Addr, err = binary.ReadUvarint(r.rd); err != nil {
+			return err
+		}
+		size, err := binary.ReadUvarint(r.rd)
+		if err != nil {
+			return err
+		}
+		m.Size = uint32(size)
+		write, err := r.rd.ReadByte()
+		if err != nil {
+			return err
+		}
+		m.Write = write != 0
+		if m.Size <= 8 {
+			if m.Value, err = binary.ReadUvarint(r.rd); err != nil {
+				return err
+			}
+		}
+		ev.Mem = append(ev.Mem, m)
+	}
+
+	ev.Err = nil
+	if flags&flagErr != 0 {
+		n, err := binary.ReadUvarint(r.rd)
+		if err != nil {
+			return err
+		}
+		if n > maxErrLen {
+			return fmt.Errorf("error message too long (%d)", n)
+		}
+		msg := make([]byte, n)
+		if _, err := io.ReadFull(r.rd, msg); err != nil {
+			return err
+		}
+		ev.Err = Error(msg)
+	}
+
+	r.st = state{
+		step:   ev.Step,
+		pc:     ev.PC,
+		cu:     ev.CUAfter,
+		regs:   ev.After,
+		primed: true,
+	}
+	return nil
+}
+
+func (r *Reader) readSlot() (sbpf.Slot, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r.rd, buf[:]); err != nil {
+		return 0, err
+	}
+	return sbpf.GetSlot(buf[:]), nil
+}
+
+func (r *Reader) readRegs(regs *[11]uint64, prev *[11]uint64) error {
+	mask, err := binary.ReadUvarint(r.rd)
+	if err != nil {
+		return err
+	}
+	if mask >= 1<<len(regs) {
+		return fmt.Errorf("invalid register mask %#x", mask)
+	}
+	*regs = *prev
+	for i := range regs {
+		if mask&(1<<i) != 0 {
+			if regs[i], err = binary.ReadUvarint(r.rd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
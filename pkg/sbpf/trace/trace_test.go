@@ -0,0 +1,151 @@
+package trace
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+const testProgram = `
+	mov64 r1, 3
+	lddw r2, 0x1122334455667788
+loop:
+	stxdw [r10-0x8], r2
+	ldxw r3, [r10-0x8]
+	add64 r2, r3
+	sub64 r1, 1
+	jne r1, 0, loop
+	mov64 r4, 0
+	div64 r2, r4
+	exit
+`
+
+// record runs the test program, returning a binary trace and a copy of each event.
+func record(t *testing.T, src string) ([]byte, []sbpf.TraceEvent) {
+	program, err := sbpf.Assemble(src)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(sbpf.NewSyscallRegistry()))
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	require.NoError(t, err)
+
+	var events []sbpf.TraceEvent
+	tracer := sbpf.TracerFunc(func(ev *sbpf.TraceEvent) {
+		w.TraceIns(ev)
+		cp := *ev
+		cp.Mem = append([]sbpf.MemAccess(nil), ev.Mem...)
+		events = append(events, cp)
+	})
+
+	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: 32 * 1024,
+		MaxCU:    1000,
+		Syscalls: sbpf.NewSyscallRegistry(),
+		Tracer:   tracer,
+	})
+	_, runErr := interpreter.Run()
+	require.ErrorIs(t, runErr, sbpf.ExcDivideByZero)
+	require.NoError(t, w.Flush())
+	return buf.Bytes(), events
+}
+
+func TestTrace_Roundtrip(t *testing.T) {
+	data, events := record(t, testProgram)
+	require.Len(t, events, 19)
+
+	first := events[0]
+	assert.Equal(t, uint64(0), first.Step)
+	assert.Equal(t, 1000, first.CUBefore)
+	assert.Equal(t, uint64(3), first.After[1])
+
+	store := events[2]
+	require.Len(t, store.Mem, 1)
+	assert.True(t, store.Mem[0].Write)
+	assert.Equal(t, uint32(8), store.Mem[0].Size)
+	assert.Equal(t, uint64(0x1122334455667788), store.Mem[0].Value)
+
+	last := events[len(events)-1]
+	assert.ErrorIs(t, last.Err, sbpf.ExcDivideByZero)
+
+	rd, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	for i := range events {
+		var ev sbpf.TraceEvent
+		require.NoError(t, rd.Next(&ev), "event %d", i)
+		want := events[i]
+		if want.Err != nil {
+			assert.EqualError(t, ev.Err, want.Err.Error())
+			want.Err, ev.Err = nil, nil
+		}
+		if len(want.Mem) == 0 {
+			want.Mem, ev.Mem = nil, nil
+		}
+		assert.Equal(t, want, ev, "event %d", i)
+	}
+	var ev sbpf.TraceEvent
+	assert.ErrorIs(t, rd.Next(&ev), io.EOF)
+}
+
+func TestTrace_Corrupt(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("NOTATRACE...")))
+	assert.Error(t, err)
+
+	data, _ := record(t, testProgram)
+	rd, err := NewReader(bytes.NewReader(data[:len(data)-3]))
+	require.NoError(t, err)
+	var ev sbpf.TraceEvent
+	for err == nil {
+		err = rd.Next(&ev)
+	}
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDiff(t *testing.T) {
+	readers := func(a, b []byte) (*Reader, *Reader) {
+		ra, err := NewReader(bytes.NewReader(a))
+		require.NoError(t, err)
+		rb, err := NewReader(bytes.NewReader(b))
+		require.NoError(t, err)
+		return ra, rb
+	}
+
+	a, _ := record(t, testProgram)
+	b, _ := record(t, testProgram)
+	ra, rb := readers(a, b)
+	div, err := Diff(ra, rb, true)
+	require.NoError(t, err)
+	assert.Nil(t, div)
+
+	c, _ := record(t, strings.Replace(testProgram, "add64 r2, r3", "add64 r2, r1", 1))
+	ra, rc := readers(a, c)
+	div, err = Diff(ra, rc, true)
+	require.NoError(t, err)
+	require.NotNil(t, div)
+	assert.Equal(t, uint64(4), div.Step)
+	assert.Equal(t, "instruction mismatch", div.Reason)
+
+	// Truncate after the first record
+	short := bytes.NewBuffer(nil)
+	w, err := NewWriter(short)
+	require.NoError(t, err)
+	rd, err := NewReader(bytes.NewReader(a))
+	require.NoError(t, err)
+	var ev sbpf.TraceEvent
+	require.NoError(t, rd.Next(&ev))
+	require.NoError(t, w.Write(&ev))
+	require.NoError(t, w.Flush())
+
+	ra, rs := readers(a, short.Bytes())
+	div, err = Diff(ra, rs, true)
+	require.NoError(t, err)
+	require.NotNil(t, div)
+	assert.Equal(t, uint64(1), div.Step)
+	assert.Equal(t, "trace b ended", div.Reason)
+	assert.Nil(t, div.B)
+}
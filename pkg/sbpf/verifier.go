@@ -1,115 +1,287 @@
 package sbpf
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
+// Verifier checks programs before execution.
+//
+// Verification happens in two passes.
+// The first pass checks each instruction in isolation.
+// The second pass builds the control flow graph and checks the program structure.
 type Verifier struct {
 	Program *Program
+
+	// Syscalls is used to check that call instructions target a known syscall.
+	// Calls that target neither a function nor a registered syscall are rejected.
+	Syscalls SyscallRegistry
+
+	// The structural checks below are off by default, as the validator does not
+	// enforce them and compiled programs routinely violate them.
+
+	// RejectUnreachable rejects code that cannot be reached from any function entry.
+	// Note that compilers emit functions that are only called indirectly (e.g. via vtables),
+	// which appear unreachable if their entry was not registered.
+	RejectUnreachable bool
+
+	// RejectFallsOffEnd rejects functions whose last instruction falls through
+	// to the next function or past the end of the text.
+	// Note that compilers end functions with calls that never return (e.g. abort or panic),
+	// which are rejected too.
+	RejectFallsOffEnd bool
+
+	// CFG is the control flow graph, set by Verify once the first pass succeeded.
+	CFG *CFG
+}
+
+// VerifyError is a verification failure at a specific instruction.
+type VerifyError struct {
+	PC  int64
+	Err error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("pc %d: %s", e.PC, e.Err)
 }
 
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Verification errors.
+//
+// Calls to unknown targets fail with ExcCallDest,
+// and division by a zero immediate with ExcDivideByZero.
+var (
+	ErrEmptyText         = errors.New("empty text")
+	ErrTextSize          = errors.New("odd .text size")
+	ErrInvalidOpcode     = errors.New("unknown opcode")
+	ErrInvalidSrcReg     = errors.New("invalid src register")
+	ErrInvalidDstReg     = errors.New("invalid dst register")
+	ErrInvalidCallSrc    = errors.New("invalid call src")
+	ErrInvalidCallxReg   = errors.New("invalid callx register")
+	ErrShiftOutOfBounds  = errors.New("shift out of bounds")
+	ErrInvalidEndianSize = errors.New("invalid bit size for endianness conversion")
+	ErrIncompleteLddw    = errors.New("incomplete lddw instruction")
+	ErrMalformedLddw     = errors.New("malformed lddw instruction")
+	ErrJumpOutOfCode     = errors.New("jump out of code")
+	ErrJumpIntoLddw      = errors.New("jump into middle of instruction")
+	ErrCallOutOfCode     = errors.New("call out of code")
+	ErrUnreachable       = errors.New("unreachable code")
+	ErrFallsOffEnd       = errors.New("function falls off its end without exit")
+)
+
 func NewVerifier(p *Program) *Verifier {
 	return &Verifier{Program: p}
 }
 
+// Verify runs both verification passes.
+//
+// Errors concerning a specific instruction are of type *VerifyError,
+// wrapping one of the verification errors above.
 func (v *Verifier) Verify() error {
 	text := v.Program.Text
 	if len(text)%SlotSize != 0 {
-		return fmt.Errorf("odd .text size")
+		return ErrTextSize
 	}
 	if len(text) == 0 {
-		return fmt.Errorf("empty text")
+		return ErrEmptyText
 	}
 
 	for pc := uint64(0); (pc+1)*SlotSize <= uint64(len(text)); pc++ {
-		insBytes := text[pc*SlotSize:]
-		ins := GetSlot(insBytes)
-
-		if ins.Src() > 10 {
-			return fmt.Errorf("invalid src register")
-		}
-		switch ins.Op() {
-		case OpLdxb, OpLdxh, OpLdxw, OpLdxdw:
-		case OpAdd32Imm, OpAdd32Reg, OpAdd64Imm, OpAdd64Reg:
-		case OpSub32Imm, OpSub32Reg, OpSub64Imm, OpSub64Reg:
-		case OpMul32Imm, OpMul32Reg, OpMul64Imm, OpMul64Reg:
-		case OpOr32Imm, OpOr32Reg, OpOr64Imm, OpOr64Reg:
-		case OpAnd32Imm, OpAnd32Reg, OpAnd64Imm, OpAnd64Reg:
-		case OpLsh32Reg, OpLsh64Reg:
-		case OpRsh32Reg, OpRsh64Reg:
-		case OpNeg32, OpNeg64:
-		case OpXor32Imm, OpXor32Reg, OpXor64Imm, OpXor64Reg:
-		case OpMov32Imm, OpMov32Reg, OpMov64Imm, OpMov64Reg:
-		case OpDiv32Reg, OpDiv64Reg:
-		case OpMod32Reg, OpMod64Reg:
-		case OpSdiv32Reg, OpSdiv64Reg:
-		case OpCall, OpExit:
-			// nothing
-		case OpStb, OpSth, OpStw, OpStdw,
-			OpStxb, OpStxh, OpStxw, OpStxdw:
-			if ins.Dst() > 10 {
-				return fmt.Errorf("invalid dst register")
-			}
-			continue
-		case OpLsh32Imm, OpRsh32Imm, OpArsh32Imm:
-			if ins.Uimm() > 31 {
-				return fmt.Errorf("32-bit shift out of bounds")
-			}
-		case OpLsh64Imm, OpRsh64Imm, OpArsh64Imm:
-			if ins.Uimm() > 63 {
-				return fmt.Errorf("64-bit shift out of bounds")
-			}
-		case OpLe, OpBe:
-			switch ins.Uimm() {
-			case 16, 32, 64:
-				// ok
-			default:
-				return fmt.Errorf("invalid bit size for endianness conversion")
-			}
-		case OpSdiv32Imm, OpSdiv64Imm:
-			fallthrough
-		case OpDiv32Imm, OpDiv64Imm, OpMod32Imm, OpMod64Imm:
-			if ins.Imm() == 0 {
-				return ExcDivideByZero
-			}
-		case OpJa,
-			OpJeqImm, OpJeqReg,
-			OpJgtImm, OpJgtReg,
-			OpJgeImm, OpJgeReg,
-			OpJltImm, OpJltReg,
-			OpJleImm, OpJleReg,
-			OpJsetImm, OpJsetReg,
-			OpJneImm, OpJneReg,
-			OpJsgtImm, OpJsgtReg,
-			OpJsgeImm, OpJsgeReg,
-			OpJsltImm, OpJsltReg,
-			OpJsleImm, OpJsleReg:
-			dst := int64(pc) + int64(ins.Off()) + 1
-			if dst < 0 || (dst*SlotSize) >= int64(len(text)) {
-				return fmt.Errorf("jump out of code")
-			}
-			dstIns := GetSlot(text[dst*SlotSize:])
-			if dstIns.Op() == 0 {
-				return fmt.Errorf("jump into middle of instruction")
-			}
-		case OpCallx:
-			if uimm := ins.Uimm(); uimm >= 10 {
-				return fmt.Errorf("invalid callx register")
+		ins := GetSlot(text[pc*SlotSize:])
+		if err := v.verifyIns(pc); err != nil {
+			return &VerifyError{PC: int64(pc), Err: err}
+		}
+		if IsLongIns(ins.Op()) {
+			pc++
+		}
+	}
+
+	v.CFG = NewCFG(v.Program)
+	return v.verifyCFG()
+}
+
+// verifyIns checks a single instruction.
+func (v *Verifier) verifyIns(pc uint64) error {
+	text := v.Program.Text
+	version := v.Program.Version
+	insBytes := text[pc*SlotSize:]
+	ins := GetSlot(insBytes)
+
+	if ins.Src() > 10 {
+		return ErrInvalidSrcReg
+	}
+	switch ins.Op() {
+	case OpLdxb, OpLdxh, OpLdxw, OpLdxdw:
+	case OpAdd32Imm, OpAdd32Reg, OpAdd64Imm, OpAdd64Reg:
+	case OpSub32Imm, OpSub32Reg, OpSub64Imm, OpSub64Reg:
+	case OpOr32Imm, OpOr32Reg, OpOr64Imm, OpOr64Reg:
+	case OpAnd32Imm, OpAnd32Reg, OpAnd64Imm, OpAnd64Reg:
+	case OpLsh32Reg, OpLsh64Reg:
+	case OpRsh32Reg, OpRsh64Reg:
+	case OpNeg32, OpNeg64:
+		if !version.EnableNeg() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+	case OpXor32Imm, OpXor32Reg, OpXor64Imm, OpXor64Reg:
+	case OpMov32Imm, OpMov32Reg, OpMov64Imm, OpMov64Reg:
+	case OpMul32Imm, OpMul32Reg, OpMul64Imm, OpMul64Reg,
+		OpDiv32Reg, OpDiv64Reg, OpMod32Reg, OpMod64Reg, OpSdiv32Reg, OpSdiv64Reg:
+		// replaced by the product/quotient/remainder class
+		if version.EnablePQR() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+	case OpExit:
+		// nothing
+	case OpCall:
+		if !version.StaticSyscalls() {
+			if err := v.checkCall(ins.Uimm(), true); err != nil {
+				return err
 			}
-		case OpLddw:
-			if len(insBytes) < 2*SlotSize {
-				return fmt.Errorf("incomplete lddw instruction")
+			break
+		}
+		switch ins.Src() {
+		case 0:
+			if err := v.checkCall(ins.Uimm(), false); err != nil {
+				return err
 			}
-			if insBytes[8] != 0 {
-				return fmt.Errorf("malformed lddw instruction")
+		case 1:
+			if err := checkJump(text, pc, int64(ins.Imm())); err != nil {
+				return fmt.Errorf("invalid call: %w", err)
 			}
-			pc++
 		default:
-			return fmt.Errorf("unknown opcode %#02x", ins.Op())
+			return fmt.Errorf("%w %d", ErrInvalidCallSrc, ins.Src())
 		}
+	case OpStb, OpSth, OpStw, OpStdw,
+		OpStxb, OpStxh, OpStxw, OpStxdw:
+		if ins.Dst() > 10 {
+			return ErrInvalidDstReg
+		}
+		return nil
+	case OpLsh32Imm, OpRsh32Imm, OpArsh32Imm:
+		if ins.Uimm() > 31 {
+			return fmt.Errorf("32-bit %w", ErrShiftOutOfBounds)
+		}
+	case OpLsh64Imm, OpRsh64Imm, OpArsh64Imm:
+		if ins.Uimm() > 63 {
+			return fmt.Errorf("64-bit %w", ErrShiftOutOfBounds)
+		}
+	case OpLe, OpBe:
+		if ins.Op() == OpLe && !version.EnableLe() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+		switch ins.Uimm() {
+		case 16, 32, 64:
+			// ok
+		default:
+			return ErrInvalidEndianSize
+		}
+	case OpSdiv32Imm, OpSdiv64Imm:
+		fallthrough
+	case OpDiv32Imm, OpDiv64Imm, OpMod32Imm, OpMod64Imm:
+		if version.EnablePQR() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+		if ins.Imm() == 0 {
+			return ExcDivideByZero
+		}
+	case OpUhmul64Imm, OpUhmul64Reg, OpShmul64Imm, OpShmul64Reg,
+		OpLmul32Imm, OpLmul32Reg, OpLmul64Imm, OpLmul64Reg,
+		OpUdiv32Reg, OpUdiv64Reg, OpUrem32Reg, OpUrem64Reg,
+		OpPqrSdiv32Reg, OpPqrSdiv64Reg, OpSrem32Reg, OpSrem64Reg:
+		if !version.EnablePQR() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+	case OpUdiv32Imm, OpUdiv64Imm, OpUrem32Imm, OpUrem64Imm,
+		OpPqrSdiv32Imm, OpPqrSdiv64Imm, OpSrem32Imm, OpSrem64Imm:
+		if !version.EnablePQR() {
+			return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+		}
+		if ins.Imm() == 0 {
+			return ExcDivideByZero
+		}
+	case OpJa,
+		OpJeqImm, OpJeqReg,
+		OpJgtImm, OpJgtReg,
+		OpJgeImm, OpJgeReg,
+		OpJltImm, OpJltReg,
+		OpJleImm, OpJleReg,
+		OpJsetImm, OpJsetReg,
+		OpJneImm, OpJneReg,
+		OpJsgtImm, OpJsgtReg,
+		OpJsgeImm, OpJsgeReg,
+		OpJsltImm, OpJsltReg,
+		OpJsleImm, OpJsleReg:
+		if err := checkJump(text, pc, int64(ins.Off())); err != nil {
+			return err
+		}
+	case OpCallx:
+		reg := ins.Uimm()
+		if version.CallxUsesSrcReg() {
+			reg = uint32(ins.Src())
+		}
+		if reg >= 10 {
+			return ErrInvalidCallxReg
+		}
+	case OpLddw:
+		if len(insBytes) < 2*SlotSize {
+			return ErrIncompleteLddw
+		}
+		if insBytes[8] != 0 {
+			return ErrMalformedLddw
+		}
+	default:
+		return fmt.Errorf("%w %#02x", ErrInvalidOpcode, ins.Op())
+	}
 
-		if ins.Dst() > 9 {
-			return fmt.Errorf("invalid dst register")
+	if ins.Dst() > 9 && !(ins.Dst() == 10 && ins.Op() == OpAdd64Imm && version.DynamicStackFrames()) {
+		return ErrInvalidDstReg
+	}
+	return nil
+}
+
+// checkCall checks the target of a call by hash.
+func (v *Verifier) checkCall(hash uint32, funcs bool) error {
+	if _, ok := v.Syscalls.Lookup(hash); ok {
+		return nil
+	}
+	if target, ok := v.Program.Funcs[hash]; ok && funcs {
+		if target < 0 || target >= int64(len(v.Program.Text)/SlotSize) {
+			return ErrCallOutOfCode
+		}
+		return nil
+	}
+	return ExcCallDest{Imm: hash}
+}
+
+// verifyCFG checks the program structure.
+func (v *Verifier) verifyCFG() error {
+	text := v.Program.Text
+	insCount := int64(len(text) / SlotSize)
+	for i := range v.CFG.Blocks {
+		b := &v.CFG.Blocks[i]
+		if !b.Reachable && v.RejectUnreachable {
+			return &VerifyError{PC: b.Start, Err: ErrUnreachable}
+		}
+		if !v.RejectFallsOffEnd || !b.FallsThrough || (b.End < insCount && !v.CFG.IsFunc(b.End)) {
+			continue
 		}
+		return &VerifyError{PC: b.Last(text), Err: ErrFallsOffEnd}
 	}
+	return nil
+}
 
+// checkJump checks the target of a relative jump or call at pc.
+func checkJump(text []byte, pc uint64, off int64) error {
+	dst := int64(pc) + off + 1
+	if dst < 0 || (dst*SlotSize) >= int64(len(text)) {
+		return ErrJumpOutOfCode
+	}
+	dstIns := GetSlot(text[dst*SlotSize:])
+	if dstIns.Op() == 0 {
+		return ErrJumpIntoLddw
+	}
 	return nil
 }
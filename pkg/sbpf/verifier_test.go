@@ -0,0 +1,179 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func verifyAsm(t *testing.T, src string, configure func(v *Verifier)) (*Verifier, error) {
+	program, err := Assemble(src)
+	require.NoError(t, err)
+	v := NewVerifier(program)
+	if configure != nil {
+		configure(v)
+	}
+	return v, v.Verify()
+}
+
+func TestVerifier_ErrorPC(t *testing.T) {
+	_, err := verifyAsm(t, `
+		mov64 r1, 1
+		div64 r1, 0
+		exit
+	`, nil)
+	var verr *VerifyError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, int64(1), verr.PC)
+	assert.ErrorIs(t, err, ExcDivideByZero)
+
+	_, err = verifyAsm(t, `
+		lddw r1, 0x1122334455667788
+		lsh64 r1, 64
+		exit
+	`, nil)
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, int64(2), verr.PC)
+}
+
+func TestVerifier_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		src    string
+		mutate func(p *Program)
+		err    error
+	}{
+		{"InvalidOpcode", "exit", func(p *Program) { p.Text[0] = 0xff }, ErrInvalidOpcode},
+		{"InvalidSrcReg", "mov64 r1, r2\nexit", func(p *Program) { p.Text[1] = 0xb1 }, ErrInvalidSrcReg},
+		{"InvalidDstReg", "mov64 r10, 1\nexit", nil, ErrInvalidDstReg},
+		{"ShiftOutOfBounds", "lsh32 r1, 32\nexit", nil, ErrShiftOutOfBounds},
+		{"InvalidEndianSize", "be64 r1\nexit", func(p *Program) { p.Text[4] = 8 }, ErrInvalidEndianSize},
+		{"InvalidCallxReg", "callx r10\nexit", nil, ErrInvalidCallxReg},
+		{"IncompleteLddw", "exit\nlddw r1, 1", func(p *Program) { p.Text = p.Text[:16] }, ErrIncompleteLddw},
+		{"MalformedLddw", "lddw r1, 1\nexit", func(p *Program) { p.Text[8] = 1 }, ErrMalformedLddw},
+		{"JumpOutOfCode", "ja 5\nexit", nil, ErrJumpOutOfCode},
+		{"JumpIntoLddw", "ja 1\nlddw r1, 1\nexit", nil, ErrJumpIntoLddw},
+		{"DivideByZero", "div64 r1, 0\nexit", nil, ExcDivideByZero},
+		{"UnknownCall", "call missing\nexit", nil, ExcCallDest{SymbolHash("missing")}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			program, err := Assemble(c.src)
+			require.NoError(t, err)
+			if c.mutate != nil {
+				c.mutate(program)
+			}
+			err = program.Verify(NewSyscallRegistry())
+			var verr *VerifyError
+			assert.ErrorAs(t, err, &verr)
+			assert.ErrorIs(t, err, c.err)
+		})
+	}
+
+	program, err := Assemble("exit")
+	require.NoError(t, err)
+	program.Text = program.Text[:0]
+	assert.ErrorIs(t, program.Verify(NewSyscallRegistry()), ErrEmptyText)
+}
+
+func TestVerifier_FallsOffEnd(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		pc   int64
+	}{
+		{"EndOfText", "mov64 r0, 0", 0},
+		{"EndOfTextJump", "exit\njeq r1, 0, -2", 1},
+		{"IntoNextFunction", "call fn\nmov64 r0, 0\nfn:\nexit", 1},
+		{"CallAtEnd", "call fn\ncall fn\nfn:\nexit", 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Not rejected by default
+			_, err := verifyAsm(t, c.src, nil)
+			require.NoError(t, err)
+
+			_, err = verifyAsm(t, c.src, func(v *Verifier) { v.RejectFallsOffEnd = true })
+			var verr *VerifyError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, c.pc, verr.PC)
+			assert.ErrorIs(t, err, ErrFallsOffEnd)
+		})
+	}
+}
+
+func TestVerifier_Unreachable(t *testing.T) {
+	src := `
+		ja skip
+		mov64 r0, 1
+	skip:
+		exit
+	`
+	v, err := verifyAsm(t, src, nil)
+	require.NoError(t, err)
+	require.NotNil(t, v.CFG.Block(1))
+	assert.False(t, v.CFG.Block(1).Reachable)
+
+	_, err = verifyAsm(t, src, func(v *Verifier) { v.RejectUnreachable = true })
+	var verr *VerifyError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, int64(1), verr.PC)
+	assert.ErrorIs(t, err, ErrUnreachable)
+}
+
+func TestVerifier_CallTarget(t *testing.T) {
+	syscalls := NewSyscallRegistry()
+	syscalls.Register("log", SyscallFunc0(func(_ VM, cuIn int) (uint64, int, error) {
+		return 0, cuIn, nil
+	}))
+	src := `
+		call log
+		call fn
+		call missing
+		exit
+	fn:
+		exit
+	`
+
+	_, err := verifyAsm(t, src, func(v *Verifier) { v.Syscalls = syscalls })
+	var verr *VerifyError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, int64(2), verr.PC)
+	assert.ErrorIs(t, err, ExcCallDest{SymbolHash("missing")})
+
+	// Without a registry, no syscalls are known
+	_, err = verifyAsm(t, src, nil)
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, int64(0), verr.PC)
+	assert.ErrorIs(t, err, ExcCallDest{SymbolHash("log")})
+}
+
+func TestCFG(t *testing.T) {
+	program, err := Assemble(`
+	entrypoint:
+		mov64 r0, 0
+		mov64 r1, 3
+	loop:
+		call fn
+		sub64 r1, 1
+		jne r1, 0, loop
+		exit
+	fn:
+		add64 r0, 1
+		exit
+	`)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(NewSyscallRegistry()))
+
+	cfg := NewCFG(program)
+	assert.Equal(t, []int64{0, 6}, cfg.Funcs)
+	assert.Equal(t, []CFGBlock{
+		{Start: 0, End: 2, Func: 0, Succs: []int64{2}, FallsThrough: true, Reachable: true},
+		{Start: 2, End: 5, Func: 0, Succs: []int64{5, 2}, FallsThrough: true, Reachable: true},
+		{Start: 5, End: 6, Func: 0, Reachable: true},
+		{Start: 6, End: 8, Func: 6, Reachable: true},
+	}, cfg.Blocks)
+	assert.Equal(t, int64(4), cfg.Block(2).Last(program.Text))
+	assert.Nil(t, cfg.Block(3))
+}
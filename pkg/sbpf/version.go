@@ -0,0 +1,66 @@
+package sbpf
+
+import "fmt"
+
+// Version is the SBF instruction set and program format revision.
+//
+// The zero value is the original SBF revision (SBFv1).
+type Version uint8
+
+const (
+	VersionV1 = Version(iota)
+	VersionV2
+)
+
+func (v Version) String() string {
+	switch v {
+	case VersionV1:
+		return "SBFv1"
+	case VersionV2:
+		return "SBFv2"
+	default:
+		return fmt.Sprintf("Version(%d)", uint8(v))
+	}
+}
+
+// DynamicStackFrames returns whether stack frames are sized by the program.
+//
+// If enabled, the frame pointer (r10) may be adjusted via add64 and
+// calls do not allocate a new frame.
+func (v Version) DynamicStackFrames() bool {
+	return v >= VersionV2
+}
+
+// EnablePQR returns whether the product/quotient/remainder instruction class is available.
+func (v Version) EnablePQR() bool {
+	return v >= VersionV2
+}
+
+// EnableNeg returns whether the neg32 and neg64 instructions are available.
+func (v Version) EnableNeg() bool {
+	return v < VersionV2
+}
+
+// EnableLe returns whether the le instruction is available.
+func (v Version) EnableLe() bool {
+	return v < VersionV2
+}
+
+// CallxUsesSrcReg returns whether callx takes the target register from the src field (instead of imm).
+func (v Version) CallxUsesSrcReg() bool {
+	return v >= VersionV2
+}
+
+// StaticSyscalls returns whether calls are resolved at compile time.
+//
+// If enabled, call instructions with src=0 invoke a syscall by hash,
+// and call instructions with src=1 jump to the relative offset in imm.
+func (v Version) StaticSyscalls() bool {
+	return v >= VersionV2
+}
+
+// EnableElfVaddr returns whether ELF sections may be placed at virtual addresses
+// that differ from their file offsets.
+func (v Version) EnableElfVaddr() bool {
+	return v >= VersionV2
+}
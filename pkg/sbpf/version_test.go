@@ -0,0 +1,164 @@
+package sbpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestV2_PQR(t *testing.T) {
+	results, err := runAsmVersion(t, VersionV2, `
+		lddw r1, 0xffffffffffffffff
+		uhmul64 r1, 2
+		call result
+		lddw r1, -2
+		mov64 r2, 3
+		shmul64 r1, r2
+		call result
+		mov64 r1, 100
+		udiv64 r1, 7
+		call result
+		mov64 r1, 100
+		urem32 r1, 7
+		call result
+		mov64 r1, -100
+		sdiv64 r1, 7
+		call result
+		mov64 r1, -100
+		srem64 r1, 7
+		call result
+		mov64 r1, 6
+		lmul64 r1, -7
+		call result
+		exit
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{
+		1,
+		0xffffffffffffffff,
+		14,
+		2,
+		uint64(-14 & (1<<64 - 1)),
+		uint64(-2 & (1<<64 - 1)),
+		uint64(-42 & (1<<64 - 1)),
+	}, results)
+}
+
+func TestV2_PQRExceptions(t *testing.T) {
+	_, err := runAsmVersion(t, VersionV2, `
+		mov64 r1, 1
+		mov64 r2, 0
+		udiv64 r1, r2
+		exit
+	`)
+	assert.ErrorIs(t, err, ExcDivideByZero)
+
+	_, err = runAsmVersion(t, VersionV2, `
+		lddw r1, 0x8000000000000000
+		mov64 r2, -1
+		sdiv64 r1, r2
+		exit
+	`)
+	assert.ErrorIs(t, err, ExcDivideOverflow)
+}
+
+func TestV2_DynamicStackFrames(t *testing.T) {
+	results, err := runAsmVersion(t, VersionV2, `
+	fn:
+		add64 r10, -64
+		stxdw [r10+0], r1
+		ldxdw r0, [r10+0]
+		exit
+
+	entrypoint:
+		mov64 r6, r10
+		stdw [r10-8], 7
+		add64 r10, -8
+		mov64 r1, 42
+		call fn
+		mov64 r1, r0
+		call result
+		mov64 r1, r6
+		sub64 r1, r10
+		call result
+		ldxdw r1, [r10+0]
+		call result
+		lddw r2, 0x100000000
+		callx r2
+		mov64 r1, r0
+		call result
+		exit
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{42, 8, 7, 7}, results)
+}
+
+func TestV2_Verifier(t *testing.T) {
+	cases := []struct {
+		name    string
+		version Version
+		src     string
+		valid   bool
+	}{
+		{"PQR_V1", VersionV1, "uhmul64 r1, 2\nexit", false},
+		{"PQR_V2", VersionV2, "uhmul64 r1, 2\nexit", true},
+		{"PQRDivByZero", VersionV2, "udiv32 r1, 0\nexit", false},
+		{"Mul_V1", VersionV1, "mul64 r1, 2\nexit", true},
+		{"Mul_V2", VersionV2, "mul64 r1, 2\nexit", false},
+		{"MulReg_V2", VersionV2, "mul32 r1, r2\nexit", false},
+		{"Div_V1", VersionV1, "div64 r1, 2\nexit", true},
+		{"Div_V2", VersionV2, "div64 r1, 2\nexit", false},
+		{"DivReg_V2", VersionV2, "div32 r1, r2\nexit", false},
+		{"Mod_V1", VersionV1, "mod64 r1, 2\nexit", true},
+		{"Mod_V2", VersionV2, "mod64 r1, 2\nexit", false},
+		{"ModReg_V2", VersionV2, "mod32 r1, r2\nexit", false},
+		{"Sdiv_V1", VersionV1, "sdiv64 r1, 2\nexit", true},
+		{"Neg_V1", VersionV1, "neg64 r1\nexit", true},
+		{"Neg_V2", VersionV2, "neg64 r1\nexit", false},
+		{"Le_V2", VersionV2, "le64 r1\nexit", false},
+		{"Be_V2", VersionV2, "be64 r1\nexit", true},
+		{"AddFramePtr_V1", VersionV1, "add64 r10, -8\nexit", false},
+		{"AddFramePtr_V2", VersionV2, "add64 r10, -8\nexit", true},
+		{"MovFramePtr_V2", VersionV2, "mov64 r10, 0\nexit", false},
+		{"Callx_V2", VersionV2, "callx r1\nexit", true},
+		{"CallxFramePtr_V2", VersionV2, "callx r10\nexit", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			program, err := AssembleVersion(c.src, c.version)
+			require.NoError(t, err)
+			if c.valid {
+				assert.NoError(t, program.Verify(NewSyscallRegistry()))
+			} else {
+				assert.Error(t, program.Verify(NewSyscallRegistry()))
+			}
+		})
+	}
+}
+
+func TestV2_VerifierCall(t *testing.T) {
+	program, err := AssembleVersion("call fn\nfn:\nexit", VersionV2)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(NewSyscallRegistry()))
+
+	// Relative call out of bounds
+	program.Text[4] = 5
+	assert.Error(t, program.Verify(NewSyscallRegistry()))
+
+	// Invalid src field
+	program.Text[4] = 0
+	program.Text[1] = 2 << 4
+	assert.Error(t, program.Verify(NewSyscallRegistry()))
+}
+
+func TestV2_VerifierLegacySdiv(t *testing.T) {
+	// The assembler maps sdiv to the PQR class in SBFv2,
+	// so assemble the legacy opcodes for SBFv1 and verify them as SBFv2.
+	for _, src := range []string{"sdiv64 r1, 2\nexit", "sdiv32 r1, r2\nexit"} {
+		program, err := AssembleVersion(src, VersionV1)
+		require.NoError(t, err)
+		program.Version = VersionV2
+		assert.Error(t, program.Verify(NewSyscallRegistry()), src)
+	}
+}
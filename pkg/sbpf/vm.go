@@ -3,11 +3,15 @@ package sbpf
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 // VM is the virtual machine abstraction, implemented by each executor.
 type VM interface {
 	VMContext() any
+	Costs() *CostTable // nil if compute units are not metered
+	HeapSize() int
 
 	Translate(addr uint64, size uint32, write bool) ([]byte, error)
 
@@ -28,22 +32,68 @@ type VM interface {
 type VMOpts struct {
 	// Machine parameters
 	HeapSize int
+	Mapping  MappingMode // memory mapping mode, aligned by default
 	Syscalls SyscallRegistry
-	Tracer   TraceSink
+	Tracer   Tracer
+	Costs    *CostTable // optional, no instruction costs if nil
+	Profiler *Profiler  // optional, records compute unit usage
+	Coverage *Coverage  // optional, records executed instructions
 
 	// Execution parameters
 	Context any // passed to syscalls
 	MaxCU   int
 	Input   []byte // mapped at VaddrInput
+
+	// Deadline optionally limits the wall-clock time of the execution.
+	// Exceeding it raises ExcDeadline.
+	Deadline time.Time
+
+	// InputRegions optionally replaces Input with custom regions in the input segment,
+	// e.g. to map account data with individual permissions.
+	// Multiple regions require MappingUnaligned.
+	InputRegions []MemoryRegion
+}
+
+// Result describes a completed program execution.
+type Result struct {
+	// R0 is the return value of the program.
+	// Sealevel programs return zero on success and an error code otherwise.
+	R0 uint64
+
+	CUUsed       int    // compute units consumed
+	CUMax        int    // compute unit limit
+	Instructions uint64 // number of instructions executed
+
+	// Final memory state, valid until the interpreter is reset.
+	Heap  []byte
+	Input []MemoryRegion // regions mapped in the input segment
 }
 
 type Exception struct {
 	PC     int64
 	Detail error
+	Stack  []StackFrame // symbolized call stack, innermost frame first
 }
 
+// Error returns a single line describing the exception and the innermost frame of its call stack.
 func (e *Exception) Error() string {
-	return fmt.Sprintf("exception at %d: %s", e.PC, e.Detail)
+	if len(e.Stack) == 0 {
+		return fmt.Sprintf("exception at %d: %s", e.PC, e.Detail)
+	}
+	frame := e.Stack[0]
+	if frame.File == "" {
+		return fmt.Sprintf("exception at %d in %s: %s", e.PC, frame.Func, e.Detail)
+	}
+	return fmt.Sprintf("exception at %d in %s %s:%d: %s", e.PC, frame.Func, frame.File, frame.Line, e.Detail)
+}
+
+// FormatStack formats the call stack of the exception, one frame per line.
+func (e *Exception) FormatStack() string {
+	var b strings.Builder
+	for i, frame := range e.Stack {
+		fmt.Fprintf(&b, "#%d %s\n", i, frame)
+	}
+	return b.String()
 }
 
 func (e *Exception) Unwrap() error {
@@ -52,10 +102,15 @@ func (e *Exception) Unwrap() error {
 
 // Exception codes.
 var (
-	ExcDivideByZero   = errors.New("division by zero")
-	ExcDivideOverflow = errors.New("divide overflow")
-	ExcOutOfCU        = errors.New("compute unit overrun")
-	ExcCallDepth      = errors.New("call depth exceeded")
+	ExcDivideByZero       = errors.New("division by zero")
+	ExcDivideOverflow     = errors.New("divide overflow")
+	ExcOutOfCU            = errors.New("compute unit overrun")
+	ExcCallDepth          = errors.New("call depth exceeded")
+	ExcInvalidInstruction = errors.New("invalid instruction")
+	ExcExecutionOverrun   = errors.New("execution overrun")
+	ExcCallOutsideText    = errors.New("callx outside of text segment")
+	ExcCancelled          = errors.New("execution cancelled")
+	ExcDeadline           = errors.New("execution deadline exceeded")
 )
 
 type ExcBadAccess struct {
@@ -0,0 +1,78 @@
+package sealevel
+
+import (
+	"errors"
+	"math"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// Heap frame limits of the compute budget program.
+const (
+	MinHeapFrameBytes         = 32 * 1024
+	MaxHeapFrameBytes         = 256 * 1024
+	HeapFrameBytesGranularity = 1024
+)
+
+var ErrInvalidHeapFrame = errors.New("invalid heap frame size")
+
+// CheckHeapFrame validates a requested heap frame size.
+func CheckHeapFrame(bytes uint32) error {
+	if bytes < MinHeapFrameBytes || bytes > MaxHeapFrameBytes || bytes%HeapFrameBytesGranularity != 0 {
+		return ErrInvalidHeapFrame
+	}
+	return nil
+}
+
+// allocAlign is the alignment of heap allocations (align_of::<u128>() on SBF).
+const allocAlign = 8
+
+// BumpAllocator is the heap allocator behind sol_alloc_free_.
+//
+// Allocations are carved off the heap in increasing order and never freed.
+type BumpAllocator struct {
+	start uint64 // VM address of the heap
+	size  uint64
+	pos   uint64 // offset of the next free byte
+}
+
+// NewBumpAllocator creates an allocator for a heap of the given size at VaddrHeap.
+func NewBumpAllocator(heapSize int) BumpAllocator {
+	return BumpAllocator{start: sbpf.VaddrHeap, size: uint64(heapSize)}
+}
+
+// Alloc returns the VM address of size bytes aligned to align,
+// or false if the heap is exhausted.
+func (a *BumpAllocator) Alloc(size, align uint64) (addr uint64, ok bool) {
+	pad := (align - (a.start+a.pos)%align) % align
+	if a.pos+pad > a.size || size > a.size-a.pos-pad {
+		return 0, false
+	}
+	a.pos += pad
+	addr = a.start + a.pos
+	a.pos += size
+	return addr, true
+}
+
+// SyscallAllocFreeImpl is the implementation of the sol_alloc_free_ syscall.
+//
+// Returns the address of the allocation, or zero if out of memory.
+// Freeing (non-zero freeAddr) is a no-op.
+func SyscallAllocFreeImpl(vm sbpf.VM, size, freeAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_alloc_free_"))
+	if cuOut < 0 {
+		return
+	}
+	// Rust rejects layouts whose padded size exceeds isize::MAX
+	if freeAddr != 0 || size > math.MaxInt64-(allocAlign-1) {
+		return
+	}
+	alloc := &syscallCtx(vm).Allocator
+	if alloc.start == 0 {
+		*alloc = NewBumpAllocator(vm.HeapSize())
+	}
+	r0, _ = alloc.Alloc(size, allocAlign)
+	return
+}
+
+var SyscallAllocFree = sbpf.SyscallFunc2(SyscallAllocFreeImpl)
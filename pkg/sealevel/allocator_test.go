@@ -0,0 +1,101 @@
+package sealevel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+func TestCheckHeapFrame(t *testing.T) {
+	assert.NoError(t, CheckHeapFrame(32*1024))
+	assert.NoError(t, CheckHeapFrame(33*1024))
+	assert.NoError(t, CheckHeapFrame(256*1024))
+	assert.ErrorIs(t, CheckHeapFrame(0), ErrInvalidHeapFrame)
+	assert.ErrorIs(t, CheckHeapFrame(31*1024), ErrInvalidHeapFrame)
+	assert.ErrorIs(t, CheckHeapFrame(32*1024+1), ErrInvalidHeapFrame)
+	assert.ErrorIs(t, CheckHeapFrame(257*1024), ErrInvalidHeapFrame)
+
+	var tx TxContext
+	assert.Equal(t, 32*1024, tx.newVMOpts(&Params{}).HeapSize)
+	require.NoError(t, tx.RequestHeapFrame(64*1024))
+	opts := tx.newVMOpts(&Params{})
+	assert.Equal(t, 64*1024, opts.HeapSize)
+	assert.Equal(t, uint64(CUHeapCost), opts.Costs.Heap(opts.HeapSize))
+	assert.Error(t, tx.RequestHeapFrame(1000))
+	assert.Equal(t, 64*1024, tx.HeapSize)
+}
+
+func TestBumpAllocator(t *testing.T) {
+	a := NewBumpAllocator(64)
+
+	addr, ok := a.Alloc(3, 8)
+	require.True(t, ok)
+	assert.Equal(t, sbpf.VaddrHeap, addr)
+
+	addr, ok = a.Alloc(8, 8)
+	require.True(t, ok)
+	assert.Equal(t, sbpf.VaddrHeap+8, addr)
+
+	addr, ok = a.Alloc(1, 1)
+	require.True(t, ok)
+	assert.Equal(t, sbpf.VaddrHeap+16, addr)
+
+	_, ok = a.Alloc(48, 8)
+	assert.False(t, ok, "padding exceeds heap")
+	addr, ok = a.Alloc(40, 8)
+	require.True(t, ok)
+	assert.Equal(t, sbpf.VaddrHeap+24, addr)
+
+	_, ok = a.Alloc(1, 1)
+	assert.False(t, ok)
+	_, ok = a.Alloc(^uint64(0), 1)
+	assert.False(t, ok)
+}
+
+func TestSyscallAllocFree(t *testing.T) {
+	program, err := sbpf.Assemble(`
+		mov64 r1, 100
+		mov64 r2, 0
+		call sol_alloc_free_
+		mov64 r6, r0
+		stxdw [r6+96], r6
+		mov64 r1, 1
+		mov64 r2, 0
+		call sol_alloc_free_
+		mov64 r7, r0
+		mov64 r1, 32768
+		mov64 r2, 0
+		call sol_alloc_free_
+		mov64 r8, r0
+		mov64 r1, 0
+		mov64 r2, r6
+		call sol_alloc_free_
+		mov64 r1, r6
+		mov64 r2, r7
+		mov64 r3, r8
+		mov64 r4, r0
+		mov64 r5, 0
+		call sol_log_64_
+		exit
+	`)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(registry))
+
+	// The allocator is sized to the heap, the 32 KiB allocation only fits the larger one
+	for heapSize, r3 := range map[int]string{32 * 1024: "0x0", 64 * 1024: "0x300000070"} {
+		var log LogCollector
+		_, err = sbpf.NewInterpreter(program, &sbpf.VMOpts{
+			HeapSize: heapSize,
+			Syscalls: registry,
+			Costs:    DefaultCosts(),
+			MaxCU:    10000,
+			Context:  &Execution{Log: &log},
+		}).Run()
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"Program log: 0x300000000, 0x300000068, " + r3 + ", 0x0, 0x0",
+		}, log.Logs)
+	}
+}
@@ -0,0 +1,176 @@
+package sealevel
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+)
+
+// ProgramLoader loads and verifies an ELF program under the given feature set.
+type ProgramLoader func(elf []byte, features *fflags.Features) (*sbpf.Program, error)
+
+// LoadProgram loads, relocates and verifies an ELF program.
+// Calls to syscalls not available under the feature set are rejected (see SyscallsFor).
+func LoadProgram(elf []byte, features *fflags.Features) (*sbpf.Program, error) {
+	ld, err := loader.NewLoaderFromBytes(elf)
+	if err != nil {
+		return nil, err
+	}
+	program, err := ld.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := program.Verify(SyscallsFor(features)); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// ProgramCache is a cache of loaded and verified programs.
+//
+// Programs are keyed by the SHA-256 hash of their ELF and the active feature set.
+// The least recently used programs are evicted once the total estimated
+// memory size exceeds the configured limit.
+// Failed loads are not cached.
+//
+// Cached programs are shared between callers and must not be modified.
+// ProgramCache is safe for concurrent use.
+type ProgramCache struct {
+	load    ProgramLoader
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[programKey]*cacheEntry
+	lru     list.List // of *cacheEntry, most recently used first
+	size    int64
+	stats   CacheStats
+}
+
+// CacheStats are statistics of a ProgramCache.
+type CacheStats struct {
+	Hits      uint64 // lookups served from the cache
+	Misses    uint64 // lookups that loaded the program
+	Evictions uint64 // programs removed to stay within the size limit
+	Entries   int    // number of cached programs
+	Size      int64  // estimated memory size of cached programs in bytes
+}
+
+type programKey struct {
+	hash     [sha256.Size]byte
+	features string
+}
+
+type cacheEntry struct {
+	key  programKey
+	elem *list.Element // nil while loading
+
+	done    chan struct{} // closed once loaded
+	program *sbpf.Program
+	err     error
+	size    int64
+}
+
+// NewProgramCache creates a cache holding up to maxSize bytes of programs.
+//
+// load defaults to LoadProgram if nil.
+func NewProgramCache(maxSize int64, load ProgramLoader) *ProgramCache {
+	if load == nil {
+		load = LoadProgram
+	}
+	return &ProgramCache{
+		load:    load,
+		maxSize: maxSize,
+		entries: make(map[programKey]*cacheEntry),
+	}
+}
+
+// Load returns the program for the given ELF, loading it on a cache miss.
+//
+// Concurrent loads of the same program wait for a single load to complete.
+func (c *ProgramCache) Load(elf []byte, features *fflags.Features) (*sbpf.Program, error) {
+	key := programKey{hash: sha256.Sum256(elf), features: features.Key()}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.stats.Hits++
+		if e.elem != nil {
+			c.lru.MoveToFront(e.elem)
+		}
+		c.mu.Unlock()
+		<-e.done
+		return e.program, e.err
+	}
+	c.stats.Misses++
+	e := &cacheEntry{key: key, done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	e.program, e.err = c.load(elf, features)
+	if e.err == nil {
+		e.size = programSize(e.program)
+	}
+	close(e.done)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.err != nil {
+		delete(c.entries, key)
+		return nil, e.err
+	}
+	e.elem = c.lru.PushFront(e)
+	c.size += e.size
+	c.evict()
+	return e.program, nil
+}
+
+// Stats returns a snapshot of the cache statistics.
+func (c *ProgramCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Size = c.size
+	return stats
+}
+
+// Purge removes all programs from the cache.
+func (c *ProgramCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		delete(c.entries, elem.Value.(*cacheEntry).key)
+	}
+	c.lru.Init()
+	c.size = 0
+}
+
+// evict removes the least recently used programs until the cache fits.
+// The most recently used program is kept even if it exceeds the limit on its own.
+func (c *ProgramCache) evict() {
+	for c.size > c.maxSize && c.lru.Len() > 1 {
+		elem := c.lru.Back()
+		e := elem.Value.(*cacheEntry)
+		c.lru.Remove(elem)
+		delete(c.entries, e.key)
+		c.size -= e.size
+		c.stats.Evictions++
+	}
+}
+
+// programSize estimates the memory used by a loaded program.
+func programSize(p *sbpf.Program) int64 {
+	const (
+		programOverhead = 256
+		mapEntrySize    = 48
+	)
+	size := int64(programOverhead + cap(p.RO))
+	size += int64(len(p.Funcs)) * mapEntrySize
+	for _, name := range p.FuncNames {
+		size += mapEntrySize + int64(len(name))
+	}
+	return size
+}
@@ -0,0 +1,131 @@
+package sealevel
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+func TestProgramCache(t *testing.T) {
+	elf := fixtures.Load(t, "sealevel", "MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr.so")
+	cache := NewProgramCache(64<<20, nil)
+
+	program, err := cache.Load(elf, nil)
+	require.NoError(t, err)
+	require.NotNil(t, program)
+
+	again, err := cache.Load(elf, new(fflags.Features))
+	require.NoError(t, err)
+	assert.Same(t, program, again, "empty feature set should match nil")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Greater(t, stats.Size, int64(len(program.Text)))
+
+	cache.Purge()
+	assert.Zero(t, cache.Stats().Entries)
+	assert.Zero(t, cache.Stats().Size)
+}
+
+func TestProgramCache_Features(t *testing.T) {
+	feature := fflags.Register([32]byte{1}, "test_feature")
+
+	var loads atomic.Int32
+	cache := NewProgramCache(1<<20, func(_ []byte, _ *fflags.Features) (*sbpf.Program, error) {
+		loads.Add(1)
+		return new(sbpf.Program), nil
+	})
+
+	elf := []byte("program")
+	_, err := cache.Load(elf, nil)
+	require.NoError(t, err)
+	_, err = cache.Load(elf, new(fflags.Features).WithFeature(feature))
+	require.NoError(t, err)
+	_, err = cache.Load(elf, new(fflags.Features).WithFeature(feature))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), loads.Load())
+	assert.Equal(t, 2, cache.Stats().Entries)
+}
+
+func TestProgramCache_Evict(t *testing.T) {
+	cache := NewProgramCache(3*1024, func(elf []byte, _ *fflags.Features) (*sbpf.Program, error) {
+		return &sbpf.Program{RO: make([]byte, 1000)}, nil
+	})
+	load := func(name string) {
+		_, err := cache.Load([]byte(name), nil)
+		require.NoError(t, err)
+	}
+
+	load("a")
+	load("b")
+	load("a") // b is now least recently used
+	load("c")
+	assert.Equal(t, uint64(1), cache.Stats().Evictions)
+	assert.Equal(t, 2, cache.Stats().Entries)
+
+	load("a")
+	load("b") // evicts c
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(4), stats.Misses)
+	assert.LessOrEqual(t, stats.Size, int64(3*1024))
+}
+
+func TestProgramCache_Error(t *testing.T) {
+	errLoad := errors.New("bad program")
+	var loads int
+	cache := NewProgramCache(1<<20, func(_ []byte, _ *fflags.Features) (*sbpf.Program, error) {
+		loads++
+		return nil, errLoad
+	})
+	for i := 0; i < 2; i++ {
+		_, err := cache.Load([]byte("program"), nil)
+		assert.ErrorIs(t, err, errLoad)
+	}
+	assert.Equal(t, 2, loads)
+	assert.Zero(t, cache.Stats().Entries)
+}
+
+func TestProgramCache_Concurrent(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	cache := NewProgramCache(1<<20, func(_ []byte, _ *fflags.Features) (*sbpf.Program, error) {
+		loads.Add(1)
+		<-release
+		return new(sbpf.Program), nil
+	})
+
+	const n = 8
+	programs := make([]*sbpf.Program, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			program, err := cache.Load([]byte("program"), nil)
+			assert.NoError(t, err)
+			programs[i] = program
+		}(i)
+	}
+	for cache.Stats().Hits+cache.Stats().Misses < n {
+		runtime.Gosched() // wait until all goroutines looked up the program
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, program := range programs {
+		assert.Same(t, programs[0], program)
+	}
+}
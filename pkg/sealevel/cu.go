@@ -1,7 +1,161 @@
 package sealevel
 
+import (
+	"sync"
+
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
 const (
-	CUSyscallBaseCost = 100
-	CUMemOpBaseCost   = 10
-	CuCpiBytesPerUnit = 250
+	CUInstructionCost          = 1
+	CUSyscallBaseCost          = 100
+	CUMemOpBaseCost            = 10
+	CuCpiBytesPerUnit          = 250
+	CUSha256BaseCost           = 85
+	CUSha256ByteCost           = 1
+	Sha256MaxSlices            = 20_000
+	CUSecp256k1RecoverCost     = 25_000
+	CUCreateProgramAddressCost = 1500
+	CUSysvarBaseCost           = 100
+	CUHeapCost                 = 8 // per 32 KiB heap page after the first
 )
+
+// syscallBaseCosts are the compute units charged by each syscall regardless of its inputs,
+// before any feature-gated change (see costChanges).
+var syscallBaseCosts = map[string]uint64{
+	"abort":                         0,
+	"sol_panic_":                    0,
+	"sol_log_":                      0,
+	"sol_log_64_":                   CUSyscallBaseCost,
+	"sol_log_compute_units_":        CUSyscallBaseCost,
+	"sol_log_pubkey":                CUSyscallBaseCost,
+	"sol_memcpy_":                   0,
+	"sol_memmove_":                  0,
+	"sol_memcmp_":                   0,
+	"sol_memset_":                   0,
+	"sol_alloc_free_":               0,
+	"sol_sha256":                    CUSha256BaseCost,
+	"sol_keccak256":                 CUSha256BaseCost,
+	"sol_blake3":                    CUSha256BaseCost,
+	"sol_secp256k1_recover":         CUSecp256k1RecoverCost,
+	"sol_create_program_address":    CUCreateProgramAddressCost,
+	"sol_try_find_program_address":  CUCreateProgramAddressCost,
+	"sol_get_clock_sysvar":          CUSysvarBaseCost,
+	"sol_get_rent_sysvar":           CUSysvarBaseCost,
+	"sol_get_epoch_schedule_sysvar": CUSysvarBaseCost,
+	"sol_get_fees_sysvar":           CUSysvarBaseCost,
+	"sol_set_return_data":           CUSyscallBaseCost,
+	"sol_get_return_data":           CUSyscallBaseCost,
+	"sol_log_data":                  CUSyscallBaseCost,
+}
+
+// costChange modifies the cost table once a feature is activated.
+type costChange struct {
+	feature fflags.Feature
+	apply   func(c *sbpf.CostTable)
+}
+
+// costChanges lists the feature-gated changes to the cost table in activation order.
+//
+// Applying all of them to the base costs yields the default costs,
+// which are those of the current cluster.
+var costChanges = []costChange{
+	{
+		// Memory syscalls, sol_log_ and hash slices are charged at least a base cost.
+		feature: FeatureUpdateSyscallBaseCosts,
+		apply: func(c *sbpf.CostTable) {
+			c.MemOpBase = CUMemOpBaseCost
+			for _, name := range []string{"sol_memcpy_", "sol_memmove_", "sol_memcmp_", "sol_memset_"} {
+				c.SetSyscall(name, CUMemOpBaseCost)
+			}
+			c.SetSyscall("sol_log_", CUSyscallBaseCost)
+		},
+	},
+}
+
+// newBaseCosts returns the cost table before any feature-gated change.
+func newBaseCosts() *sbpf.CostTable {
+	c := sbpf.NewCostTable(CUInstructionCost)
+	c.CPIBytesPerUnit = CuCpiBytesPerUnit
+	c.HashByteCost = CUSha256ByteCost
+	c.HashMaxSlices = Sha256MaxSlices
+	c.HeapPageCost = CUHeapCost
+	for name, cost := range syscallBaseCosts {
+		c.SetSyscall(name, cost)
+	}
+	return c
+}
+
+var defaultCosts = newDefaultCosts()
+
+func newDefaultCosts() *sbpf.CostTable {
+	c := newBaseCosts()
+	for _, change := range costChanges {
+		change.apply(c)
+	}
+	return c
+}
+
+// DefaultCosts returns the cost table with all feature-gated changes applied.
+//
+// The returned table is shared and must not be modified.
+func DefaultCosts() *sbpf.CostTable {
+	return defaultCosts
+}
+
+// costSchedule derives cost tables from a list of feature-gated changes.
+type costSchedule struct {
+	base    func() *sbpf.CostTable
+	changes []costChange
+	cache   sync.Map // map[string]*sbpf.CostTable, keyed by feature set
+}
+
+func newCostSchedule(base func() *sbpf.CostTable, changes []costChange) *costSchedule {
+	return &costSchedule{base: base, changes: changes}
+}
+
+// costsFor returns the cost table active under the given feature set.
+func (s *costSchedule) costsFor(features *fflags.Features) *sbpf.CostTable {
+	key := features.Key()
+	if c, ok := s.cache.Load(key); ok {
+		return c.(*sbpf.CostTable)
+	}
+	c := s.base()
+	for _, change := range s.changes {
+		if features.HasFeature(change.feature) {
+			change.apply(c)
+		}
+	}
+	actual, _ := s.cache.LoadOrStore(key, c)
+	return actual.(*sbpf.CostTable)
+}
+
+var schedule = newCostSchedule(newBaseCosts, costChanges)
+
+// CostsFor returns the cost table active under the given feature set.
+// A nil feature set selects the default costs.
+//
+// The returned table is shared and must not be modified.
+func CostsFor(features *fflags.Features) *sbpf.CostTable {
+	if features == nil {
+		return defaultCosts
+	}
+	return schedule.costsFor(features)
+}
+
+// vmCosts returns the cost table of the VM, falling back to the default costs.
+func vmCosts(vm sbpf.VM) *sbpf.CostTable {
+	if c := vm.Costs(); c != nil {
+		return c
+	}
+	return defaultCosts
+}
+
+// AnalyzeCU computes static compute unit bounds of a verified program
+// using the Sealevel costs active under the given feature set.
+//
+// cfg may be nil, see sbpf.AnalyzeCU.
+func AnalyzeCU(p *sbpf.Program, cfg *sbpf.CFG, features *fflags.Features) sbpf.CUBounds {
+	return sbpf.AnalyzeCU(p, cfg, CostsFor(features))
+}
@@ -0,0 +1,58 @@
+package sealevel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/sbpf/loader"
+)
+
+func TestSyscallBaseCosts(t *testing.T) {
+	costs := DefaultCosts().Syscalls
+	for _, hash := range registry.Hashes() {
+		name, _ := registry.Name(hash)
+		assert.Contains(t, costs, hash, "missing base cost of %s", name)
+	}
+}
+
+func TestAnalyzeCU(t *testing.T) {
+	ld, err := loader.NewLoaderFromBytes(fixtures.Load(t, "sbpf", "pass_stack_reference.so"))
+	require.NoError(t, err)
+	program, err := ld.Load()
+	require.NoError(t, err)
+
+	verifier := sbpf.NewVerifier(program)
+	require.NoError(t, verifier.Verify())
+
+	bounds := AnalyzeCU(program, verifier.CFG, nil)
+	entry, ok := bounds.Func(int64(program.Entrypoint))
+	require.True(t, ok)
+	require.NoError(t, entry.Err)
+	assert.Equal(t, uint64(29), entry.CU)
+	assert.True(t, entry.Fits(1_400_000))
+}
+
+func TestCostsFor(t *testing.T) {
+	assert.Same(t, DefaultCosts(), CostsFor(nil))
+
+	base := CostsFor(new(fflags.Features))
+	assert.Same(t, base, CostsFor(new(fflags.Features)))
+	assert.Zero(t, base.MemOpBase)
+	assert.Zero(t, base.Syscall("sol_log_"))
+	assert.Zero(t, base.Syscall("sol_memcpy_"))
+	assert.Equal(t, uint64(1), base.MemOp(300))
+
+	features := new(fflags.Features).WithFeature(FeatureUpdateSyscallBaseCosts)
+	costs := CostsFor(features)
+	assert.Same(t, costs, CostsFor(features.Clone()))
+	assert.Equal(t, DefaultCosts(), costs)
+	assert.Equal(t, uint64(CUMemOpBaseCost), costs.MemOp(300))
+	assert.Equal(t, uint64(CUSyscallBaseCost), costs.Syscall("sol_log_"))
+
+	// Features unrelated to costs keep the base costs.
+	assert.Equal(t, base, CostsFor(new(fflags.Features).WithFeature(FeatureAccountDataDirectMapping)))
+}
@@ -0,0 +1,123 @@
+package sealevel
+
+import (
+	"errors"
+	"fmt"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/solana"
+)
+
+// builtinErrorShift is the bit position of builtin program error codes.
+// Error codes below 1<<32 are custom program errors.
+const builtinErrorShift = 32
+
+// builtinErrors are the messages of builtin program errors by code.
+// Indexes are shifted right by builtinErrorShift.
+var builtinErrors = [...]string{
+	2:  "invalid program argument",
+	3:  "invalid instruction data",
+	4:  "invalid account data for instruction",
+	5:  "account data too small for instruction",
+	6:  "insufficient funds for instruction",
+	7:  "incorrect program id for instruction",
+	8:  "missing required signature for instruction",
+	9:  "instruction requires an uninitialized account",
+	10: "instruction requires an initialized account",
+	11: "insufficient account keys for instruction",
+	12: "instruction tries to borrow reference for an account which is already borrowed",
+	13: "Length of the seed is too long for address generation",
+	14: "Provided seeds do not result in a valid address",
+	15: "Failed to serialize or deserialize account data: Unknown",
+	16: "An account does not have enough lamports to be rent-exempt",
+	17: "Unsupported sysvar",
+	18: "Provided owner is not allowed",
+	19: "Accounts data allocations exceeded the maximum allowed per transaction",
+	20: "Failed to reallocate account data",
+	21: "Max instruction trace length exceeded",
+	22: "Builtin programs must consume compute units",
+	23: "Invalid account owner",
+	24: "Program arithmetic overflowed",
+	25: "Account is immutable",
+	26: "Incorrect authority provided",
+}
+
+var ErrInvalidProgramError = errors.New("program returned invalid error code")
+
+// ProgramError converts the non-zero return value of a program to an error.
+//
+// Messages match the instruction errors reported by the validator.
+func ProgramError(code uint64) error {
+	if code>>builtinErrorShift == 0 {
+		return fmt.Errorf("custom program error: %#x", code)
+	}
+	if code&(1<<builtinErrorShift-1) != 0 {
+		return ErrInvalidProgramError
+	}
+	builtin := code >> builtinErrorShift
+	if builtin == 1 {
+		return fmt.Errorf("custom program error: %#x", 0)
+	}
+	if builtin < uint64(len(builtinErrors)) && builtinErrors[builtin] != "" {
+		return errors.New(builtinErrors[builtin])
+	}
+	return ErrInvalidProgramError
+}
+
+// vmErrors maps VM exceptions to the validator's messages.
+var vmErrors = []struct {
+	err error
+	msg string
+}{
+	{sbpf.ExcDivideByZero, "divide by zero at BPF instruction"},
+	{sbpf.ExcDivideOverflow, "division overflow at BPF instruction"},
+	{sbpf.ExcOutOfCU, "exceeded CUs meter at BPF instruction"},
+	{sbpf.ExcCallDepth, "exceeded max BPF to BPF call depth"},
+	{sbpf.ExcInvalidInstruction, "invalid BPF instruction"},
+	{sbpf.ExcExecutionOverrun, "attempted to execute past the end of the text segment at BPF instruction"},
+	{sbpf.ExcCallOutsideText, "callx attempted to call outside of the text segment"},
+}
+
+// failureMessage returns the validator's message for an execution error.
+//
+// VM exceptions are reported without their location.
+// Syscall errors already carry the validator's message,
+// except for those shared with package solana.
+func failureMessage(err error) string {
+	var exc *sbpf.Exception
+	if errors.As(err, &exc) {
+		err = exc.Detail
+	}
+	var access sbpf.ExcBadAccess
+	switch {
+	case errors.As(err, &access):
+		return fmt.Sprintf("Access violation in %s section at address %#x of size %d",
+			sectionName(access.Addr), access.Addr, access.Size)
+	case errors.As(err, new(sbpf.ExcCallDest)):
+		return "unsupported BPF instruction"
+	case errors.Is(err, solana.ErrMaxSeedLengthExceeded):
+		return "Could not create program address with signer seeds: Length of the seed is too long for address generation"
+	}
+	for _, e := range vmErrors {
+		if errors.Is(err, e.err) {
+			return e.msg
+		}
+	}
+	return err.Error()
+}
+
+// sectionName returns the validator's name of the memory region containing addr.
+func sectionName(addr uint64) string {
+	switch addr >> 32 {
+	case sbpf.VaddrProgram >> 32:
+		return "program"
+	case sbpf.VaddrStack >> 32:
+		return "stack"
+	case sbpf.VaddrHeap >> 32:
+		return "heap"
+	case sbpf.VaddrInput >> 32:
+		return "input"
+	default:
+		return "unknown"
+	}
+}
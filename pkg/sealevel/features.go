@@ -0,0 +1,26 @@
+package sealevel
+
+import (
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/solana"
+)
+
+// Features affecting program execution.
+var (
+	// FeatureAccountDataDirectMapping maps account data into the input segment
+	// instead of copying it into the serialized parameters.
+	FeatureAccountDataDirectMapping = fflags.Register(
+		solana.MustAddress("EenyoWx9UMXYKpR8mW5Jmfmy2fRjzUtM7NduYMY8bx33"),
+		"bpf_account_data_direct_mapping")
+
+	// FeatureUpdateSyscallBaseCosts charges a base cost for memory syscalls,
+	// sol_log_ and hash slices (see costChanges).
+	FeatureUpdateSyscallBaseCosts = fflags.Register(
+		solana.MustAddress("2h63t332mGCCsWK2nqqqHhN4U9ayyqhLVFvczznHDoTZ"),
+		"update_syscall_base_costs")
+
+	// FeatureDisableFeesSysvar removes the sol_get_fees_sysvar syscall.
+	FeatureDisableFeesSysvar = fflags.Register(
+		solana.MustAddress("JAN1trEUEtZjgXYzNBYHU9DYd7GnThhXfFP7SzPXkPsG"),
+		"disable_fees_sysvar")
+)
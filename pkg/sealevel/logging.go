@@ -1,13 +1,89 @@
 package sealevel
 
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
 type Logger interface {
 	Log(s string)
 }
 
-type LogRecorder struct {
-	Logs []string
+// LogMessagesBytesLimit is the maximum number of bytes logged per transaction by the validator.
+const LogMessagesBytesLimit = 10_000
+
+// LogCollector collects program logs like the validator.
+//
+// Once a message would reach the byte limit, it and all further messages are
+// dropped and a single "Log truncated" message is recorded instead.
+type LogCollector struct {
+	Logs       []string
+	BytesLimit int // zero means unlimited
+
+	bytesWritten int
+	truncated    bool
+}
+
+// NewLogCollector creates a log collector with the given byte limit.
+func NewLogCollector(bytesLimit int) *LogCollector {
+	return &LogCollector{BytesLimit: bytesLimit}
+}
+
+func (c *LogCollector) Log(s string) {
+	if c.BytesLimit == 0 {
+		c.Logs = append(c.Logs, s)
+		return
+	}
+	if c.bytesWritten+len(s) >= c.BytesLimit {
+		if !c.truncated {
+			c.truncated = true
+			c.Logs = append(c.Logs, "Log truncated")
+		}
+		return
+	}
+	c.bytesWritten += len(s)
+	c.Logs = append(c.Logs, s)
+}
+
+// LogInvoke logs the start of a program invocation at the given depth, starting at 1.
+func LogInvoke(log Logger, programID solana.PublicKey, depth int) {
+	log.Log(fmt.Sprintf("Program %s invoke [%d]", programID, depth))
+}
+
+// LogConsumed logs the compute units consumed by a program invocation.
+func LogConsumed(log Logger, programID solana.PublicKey, used, max int) {
+	log.Log(fmt.Sprintf("Program %s consumed %d of %d compute units", programID, used, max))
+}
+
+// LogReturnData logs non-empty return data like the validator.
+//
+// The line names the program that just finished executing,
+// which may differ from the program that set the return data.
+func LogReturnData(log Logger, programID solana.PublicKey, r *ReturnData) {
+	if len(r.Data) == 0 {
+		return
+	}
+	log.Log("Program return: " + programID.String() + " " + base64.StdEncoding.EncodeToString(r.Data))
+}
+
+// LogData logs the fields passed to sol_log_data.
+func LogData(log Logger, fields [][]byte) {
+	encoded := make([]string, len(fields))
+	for i, field := range fields {
+		encoded[i] = base64.StdEncoding.EncodeToString(field)
+	}
+	log.Log("Program data: " + strings.Join(encoded, " "))
+}
+
+// LogSuccess logs the successful completion of a program invocation.
+func LogSuccess(log Logger, programID solana.PublicKey) {
+	log.Log(fmt.Sprintf("Program %s success", programID))
 }
 
-func (r *LogRecorder) Log(s string) {
-	r.Logs = append(r.Logs, s)
+// LogFailure logs the failure of a program invocation.
+func LogFailure(log Logger, programID solana.PublicKey, err error) {
+	log.Log(fmt.Sprintf("Program %s failed: %s", programID, failureMessage(err)))
 }
@@ -0,0 +1,133 @@
+package sealevel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/solana"
+)
+
+func TestLogCollector(t *testing.T) {
+	c := NewLogCollector(10)
+	c.Log("abcd")
+	c.Log("efghi")
+	c.Log("j") // reaches the limit
+	c.Log("k")
+	c.Log("") // still fits, like in the validator
+	assert.Equal(t, []string{"abcd", "efghi", "Log truncated", ""}, c.Logs)
+
+	var unlimited LogCollector
+	for i := 0; i < 100; i++ {
+		unlimited.Log(strings.Repeat("x", 1000))
+	}
+	assert.Len(t, unlimited.Logs, 100)
+}
+
+func TestProgramError(t *testing.T) {
+	cases := []struct {
+		code uint64
+		msg  string
+	}{
+		{1, "custom program error: 0x1"},
+		{0xffffffff, "custom program error: 0xffffffff"},
+		{1 << 32, "custom program error: 0x0"},
+		{2 << 32, "invalid program argument"},
+		{14 << 32, "Provided seeds do not result in a valid address"},
+		{26 << 32, "Incorrect authority provided"},
+		{27 << 32, "program returned invalid error code"},
+		{2<<32 | 1, "program returned invalid error code"},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, ProgramError(c.code), c.msg, "code %#x", c.code)
+	}
+}
+
+func TestLogFailure(t *testing.T) {
+	// Messages as logged by the validator
+	cases := []struct {
+		err error
+		msg string
+	}{
+		{&sbpf.Exception{PC: 3, Detail: sbpf.ExcOutOfCU}, "exceeded CUs meter at BPF instruction"},
+		{&sbpf.Exception{PC: 3, Detail: sbpf.ExcDivideByZero}, "divide by zero at BPF instruction"},
+		{&sbpf.Exception{PC: 3, Detail: sbpf.ExcCallDepth}, "exceeded max BPF to BPF call depth"},
+		{
+			&sbpf.Exception{PC: 3, Detail: sbpf.NewExcBadAccess(0x400000060, 8, false, "out-of-bounds access")},
+			"Access violation in input section at address 0x400000060 of size 8",
+		},
+		{
+			&sbpf.Exception{PC: 3, Detail: sbpf.NewExcBadAccess(0x100000010, 1, true, "write to read-only region")},
+			"Access violation in program section at address 0x100000010 of size 1",
+		},
+		{&sbpf.Exception{PC: 3, Detail: ErrAbort}, "SBF program panicked"},
+		{
+			&sbpf.Exception{PC: 3, Detail: &PanicError{File: "src/lib.rs", Line: 10, Column: 5}},
+			"SBF program Panicked in src/lib.rs at 10:5",
+		},
+		{&sbpf.Exception{PC: 3, Detail: ErrTooManySlices}, "Hashing too many sequences"},
+		{&sbpf.Exception{PC: 3, Detail: ErrCopyOverlapping}, "Overlapping copy"},
+		{
+			&sbpf.Exception{PC: 3, Detail: solana.ErrMaxSeedLengthExceeded},
+			"Could not create program address with signer seeds: Length of the seed is too long for address generation",
+		},
+		{ProgramError(1), "custom program error: 0x1"},
+	}
+	for _, c := range cases {
+		var log LogCollector
+		LogFailure(&log, testProgramID, c.err)
+		assert.Equal(t, []string{"Program BPFLoaderUpgradeab1e11111111111111111111111 failed: " + c.msg}, log.Logs)
+	}
+
+	// Errors that carry their arguments
+	_, _, _, err := runReturnDataAsm(t, MaxReturnData+1, 3, 32)
+	require.Error(t, err)
+	assert.Equal(t, "Return data too large (1025 > 1024)", failureMessage(err))
+}
+
+func TestTxContext_Execute_Failure(t *testing.T) {
+	cases := []struct {
+		src string
+		msg string
+	}{
+		{"mov64 r0, 1\nmov64 r1, 0\ndiv64 r0, r1\nexit", "divide by zero at BPF instruction"},
+		{"ldxdw r0, [r1+0x100]\nexit", "Access violation in input section at address 0x400000100 of size 8"},
+		{"call abort\nexit", "SBF program panicked"},
+	}
+	for _, c := range cases {
+		program, err := sbpf.Assemble(c.src)
+		require.NoError(t, err)
+
+		var tx TxContext
+		_, err = tx.Execute(program, &Params{ProgramID: testProgramID})
+		require.Error(t, err)
+		require.Len(t, tx.Log.Logs, 3)
+		assert.Equal(t, "Program BPFLoaderUpgradeab1e11111111111111111111111 failed: "+c.msg, tx.Log.Logs[2])
+	}
+}
+
+func TestSyscallLogData(t *testing.T) {
+	// Logs the slices "ab" and ""
+	var log LogCollector
+	res, err := runSyscallAsm(t, `
+		stb [r10-8], 0x61
+		stb [r10-7], 0x62
+		mov64 r2, r10
+		sub64 r2, 8
+		stxdw [r10-48], r2
+		stdw [r10-40], 2
+		stdw [r10-32], 0
+		stdw [r10-24], 0
+		mov64 r1, r10
+		sub64 r1, 48
+		mov64 r2, 2
+		call sol_log_data
+		exit
+	`, &Execution{Log: &log}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Program data: YWI= "}, log.Logs)
+	// base cost, base cost per slice and one unit per byte
+	assert.Equal(t, int(res.Instructions)+3*CUSyscallBaseCost+2, res.CUUsed)
+}
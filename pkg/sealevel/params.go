@@ -7,6 +7,7 @@ import (
 	"io"
 
 	"github.com/gagliardetto/solana-go"
+	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
 // Params is the data passed to programs via the Sealevel VM input segment.
@@ -39,8 +40,60 @@ type AccountParam struct {
 
 // Serialize writes the params to the provided buffer.
 func (p *Params) Serialize(buf *bytes.Buffer) {
+	p.serialize(buf, false)
+}
+
+// SerializeRegions writes the params to the provided buffer and
+// returns the input memory regions starting at sbpf.VaddrInput.
+//
+// Account data is not copied into the buffer but directly mapped as
+// a separate region, followed by a region for its realloc padding.
+// Both are writable only if the account is writable.
+// All other regions are backed by the buffer.
+func (p *Params) SerializeRegions(buf *bytes.Buffer) []sbpf.MemoryRegion {
+	dataOffs := p.serialize(buf, true)
+	mem := buf.Bytes()
+
+	var regions []sbpf.MemoryRegion
+	vaddr := sbpf.VaddrInput
+	addRegion := func(data []byte, writable bool) {
+		if len(data) == 0 {
+			return
+		}
+		regions = append(regions, sbpf.MemoryRegion{
+			Vaddr:    vaddr,
+			Data:     data,
+			Writable: writable,
+		})
+		vaddr += uint64(len(data))
+	}
+
+	var pos int
+	for _, d := range dataOffs {
+		addRegion(mem[pos:d.off:d.off], true)
+		addRegion(d.acc.Data, d.acc.IsWritable)
+		pos = d.off + d.acc.Padding
+		addRegion(mem[d.off:pos:pos], d.acc.IsWritable)
+	}
+	addRegion(mem[pos:], true)
+	return regions
+}
+
+// accountDataOff is the position of unmapped account data in the serialized buffer.
+type accountDataOff struct {
+	off int
+	acc *AccountParam
+}
+
+// serialize writes the params to the provided buffer.
+//
+// If skipData is set, account data is left out of the buffer and
+// its offsets are returned instead.
+// The layout (including padding) is the same either way.
+func (p *Params) serialize(buf *bytes.Buffer, skipData bool) (dataOffs []accountDataOff) {
 	buf.Reset()
 
+	var skipped int
 	_ = binary.Write(buf, binary.LittleEndian, uint64(len(p.Accounts)))
 	for i := range p.Accounts {
 		acc := &p.Accounts[i]
@@ -60,11 +113,16 @@ func (p *Params) Serialize(buf *bytes.Buffer) {
 		_ = binary.Write(buf, binary.LittleEndian, acc.Lamports)
 
 		_ = binary.Write(buf, binary.LittleEndian, uint64(len(acc.Data)))
-		// This account copy cannot be avoided without a significant redesign of the VM
-		_, _ = buf.Write(acc.Data[:])
+		if skipData {
+			dataOffs = append(dataOffs, accountDataOff{off: buf.Len(), acc: acc})
+			skipped += len(acc.Data)
+		} else {
+			// This account copy cannot be avoided without a significant redesign of the VM
+			_, _ = buf.Write(acc.Data[:])
+		}
 
 		acc.Padding = ReallocSpace
-		if offset := buf.Len() % ReallocAlign; offset != 0 {
+		if offset := (buf.Len() + skipped) % ReallocAlign; offset != 0 {
 			acc.Padding += ReallocAlign - offset
 		}
 		_ = writeZeros(buf, acc.Padding)
@@ -79,28 +137,37 @@ func (p *Params) Serialize(buf *bytes.Buffer) {
 	if err != nil {
 		panic("writes to buffer failed: " + err.Error()) // OOM
 	}
+	return
 }
 
 // Update writes data modified by a program back to the params struct.
 func (p *Params) Update(buf *bytes.Reader) error {
 	// TODO authorization checks
 
-	for i := 0; true; i++ {
-		if i >= len(p.Accounts) {
-			return fmt.Errorf("number of accounts changed")
-		}
+	var numAccounts uint64
+	if err := binary.Read(buf, binary.LittleEndian, &numAccounts); err != nil {
+		return err
+	}
+	if numAccounts != uint64(len(p.Accounts)) {
+		return fmt.Errorf("number of accounts changed")
+	}
+
+	for i := range p.Accounts {
 		acc := &p.Accounts[i]
 
 		idx, err := buf.ReadByte()
 		if err != nil {
 			return err
 		}
-		if (!acc.IsDuplicate && idx != 0xFF) || acc.DuplicateIndex != idx {
-			return fmt.Errorf("account order changed")
+		if acc.IsDuplicate {
+			if idx != acc.DuplicateIndex {
+				return fmt.Errorf("account order changed")
+			}
+			_, _ = buf.Seek(7, io.SeekCurrent)
+			continue
 		}
-
 		if idx != 0xFF {
-			continue
+			return fmt.Errorf("account order changed")
 		}
 
 		// TODO is deferring error check okay here?
@@ -127,11 +194,22 @@ func (p *Params) Update(buf *bytes.Reader) error {
 		_ = binary.Read(buf, binary.LittleEndian, &acc.RentEpoch)
 	}
 
-	_, _ = buf.Seek(int64(len(p.Data)), io.SeekCurrent)
+	_, _ = buf.Seek(8+int64(len(p.Data)), io.SeekCurrent)
 	_, err := buf.Read(p.ProgramID[:])
 	return err
 }
 
+// UpdateRegions writes data modified by a program back to the params struct.
+//
+// The regions must be the ones returned by SerializeRegions.
+func (p *Params) UpdateRegions(regions []sbpf.MemoryRegion) error {
+	var buf bytes.Buffer
+	for _, r := range regions {
+		_, _ = buf.Write(r.Data)
+	}
+	return p.Update(bytes.NewReader(buf.Bytes()))
+}
+
 func writeZeros(b *bytes.Buffer, n int) error {
 	_, err := io.Copy(b, io.LimitReader(zeroRd{}, int64(n)))
 	return err
@@ -0,0 +1,95 @@
+package sealevel
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+func testParams() *Params {
+	return &Params{
+		Accounts: []AccountParam{
+			{
+				IsSigner:   true,
+				IsWritable: true,
+				Key:        [32]byte{1},
+				Lamports:   100,
+				Data:       []byte{1, 2, 3},
+			},
+			{
+				IsDuplicate:    true,
+				DuplicateIndex: 0,
+			},
+			{
+				Key:      [32]byte{2},
+				Lamports: 200,
+				Data:     []byte{4, 5, 6, 7, 8},
+			},
+			{
+				Key:  [32]byte{3},
+				Data: []byte{},
+			},
+		},
+		Data:      []byte("hello"),
+		ProgramID: [32]byte{4},
+	}
+}
+
+func TestParams_SerializeRegions(t *testing.T) {
+	var flat bytes.Buffer
+	expected := testParams()
+	expected.Serialize(&flat)
+
+	var buf bytes.Buffer
+	params := testParams()
+	regions := params.SerializeRegions(&buf)
+
+	var joined []byte
+	vaddr := sbpf.VaddrInput
+	for _, r := range regions {
+		assert.Equal(t, vaddr, r.Vaddr)
+		vaddr += uint64(len(r.Data))
+		joined = append(joined, r.Data...)
+	}
+	assert.Equal(t, flat.Bytes(), joined)
+
+	// Account data is mapped directly, followed by its realloc padding
+	require.Len(t, regions, 9)
+	writable := []bool{true, true, true, true, false, false, true, false, true}
+	for i, r := range regions {
+		assert.Equal(t, writable[i], r.Writable, "region %d", i)
+	}
+	assert.Equal(t, &params.Accounts[0].Data[0], &regions[1].Data[0])
+	assert.Len(t, regions[2].Data, params.Accounts[0].Padding)
+	assert.Equal(t, &params.Accounts[2].Data[0], &regions[4].Data[0])
+	assert.Len(t, regions[5].Data, params.Accounts[2].Padding)
+	assert.Len(t, regions[7].Data, params.Accounts[3].Padding)
+
+	require.NoError(t, params.UpdateRegions(regions))
+	assert.Equal(t, expected, params)
+}
+
+func TestParams_SerializeRegions_ReadOnly(t *testing.T) {
+	var buf bytes.Buffer
+	params := testParams()
+	regions := params.SerializeRegions(&buf)
+
+	mapping, err := sbpf.NewMemoryMapping(sbpf.MappingUnaligned, regions)
+	require.NoError(t, err)
+
+	mem, err := mapping.Translate(regions[1].Vaddr, 3, true)
+	require.NoError(t, err)
+	copy(mem, []byte{9, 9, 9})
+	assert.Equal(t, []byte{9, 9, 9}, params.Accounts[0].Data)
+
+	// Data and realloc padding of read-only accounts
+	for _, r := range []sbpf.MemoryRegion{regions[4], regions[5]} {
+		_, err = mapping.Translate(r.Vaddr, 1, true)
+		assert.Error(t, err)
+		_, err = mapping.Translate(r.Vaddr, 1, false)
+		assert.NoError(t, err)
+	}
+}
@@ -3,26 +3,116 @@ package sealevel
 import (
 	"bytes"
 
+	"github.com/gagliardetto/solana-go"
+	"go.firedancer.io/radiance/pkg/fflags"
 	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
-type TxContext struct{}
+type TxContext struct {
+	Features *fflags.Features // active features, selects costs and input mapping
+	HeapSize int              // heap frame size, MinHeapFrameBytes if zero
+	Sysvars  SysvarCache      // sysvars of the current slot
+
+	// Log collects the program logs of the transaction.
+	// Created with LogMessagesBytesLimit if nil.
+	Log *LogCollector
+
+	// ReturnData is the return data of the last executed program.
+	ReturnData ReturnData
+}
+
+// RequestHeapFrame sets the heap size of program executions in the transaction,
+// as requested by the compute budget program.
+func (t *TxContext) RequestHeapFrame(bytes uint32) error {
+	if err := CheckHeapFrame(bytes); err != nil {
+		return err
+	}
+	t.HeapSize = int(bytes)
+	return nil
+}
+
+func (t *TxContext) logCollector() *LogCollector {
+	if t.Log == nil {
+		t.Log = NewLogCollector(LogMessagesBytesLimit)
+	}
+	return t.Log
+}
+
+func (t *TxContext) heapSize() int {
+	if t.HeapSize == 0 {
+		return MinHeapFrameBytes
+	}
+	return t.HeapSize
+}
 
 type Execution struct {
-	Log Logger
+	Log        Logger
+	Allocator  BumpAllocator // sized to the VM heap if zero
+	Sysvars    SysvarCache
+	ProgramID  solana.PublicKey // currently executing program
+	ReturnData ReturnData       // set via sol_set_return_data
+}
+
+// ReturnData is data returned by a program to its caller.
+type ReturnData struct {
+	ProgramID solana.PublicKey // program that set the data
+	Data      []byte
+}
+
+// Result describes a completed program execution.
+type Result struct {
+	sbpf.Result
+	ReturnData ReturnData
+}
+
+// Execute runs a program with the given parameters as a top-level instruction.
+//
+// Logs the invocation like the validator.
+// A non-zero program return value is converted to an error via ProgramError.
+// The return data of the program is kept in the transaction context.
+func (t *TxContext) Execute(program *sbpf.Program, params *Params) (Result, error) {
+	opts := t.newVMOpts(params)
+	execution := opts.Context.(*Execution)
+	LogInvoke(execution.Log, params.ProgramID, 1)
+	res, err := sbpf.NewInterpreter(program, opts).Run()
+	LogConsumed(execution.Log, params.ProgramID, res.CUUsed, res.CUMax)
+	LogReturnData(execution.Log, params.ProgramID, &execution.ReturnData)
+	if err == nil && res.R0 != 0 {
+		err = ProgramError(res.R0)
+	}
+	if err != nil {
+		LogFailure(execution.Log, params.ProgramID, err)
+	} else {
+		LogSuccess(execution.Log, params.ProgramID)
+	}
+	t.ReturnData = execution.ReturnData
+	return Result{
+		Result:     res,
+		ReturnData: execution.ReturnData,
+	}, err
 }
 
 func (t *TxContext) newVMOpts(params *Params) *sbpf.VMOpts {
+	heapSize := t.heapSize()
 	execution := &Execution{
-		Log: new(LogRecorder),
+		Log:       t.logCollector(),
+		Sysvars:   t.Sysvars,
+		ProgramID: params.ProgramID,
 	}
-	var buf bytes.Buffer
-	params.Serialize(&buf)
-	return &sbpf.VMOpts{
-		HeapSize: 32 * 1024,
-		Syscalls: registry,
+	opts := &sbpf.VMOpts{
+		HeapSize: heapSize,
+		Syscalls: SyscallsFor(t.Features),
+		Costs:    CostsFor(t.Features),
 		Context:  execution,
 		MaxCU:    1_400_000,
-		Input:    buf.Bytes(),
 	}
+	var buf bytes.Buffer
+	if t.Features.HasFeature(FeatureAccountDataDirectMapping) {
+		opts.Mapping = sbpf.MappingUnaligned
+		opts.InputRegions = params.SerializeRegions(&buf)
+	} else {
+		params.Serialize(&buf)
+		opts.Input = buf.Bytes()
+	}
+	return opts
 }
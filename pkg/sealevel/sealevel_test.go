@@ -1,6 +1,7 @@
 package sealevel
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
 	"io/fs"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.firedancer.io/radiance/fixtures"
+	"go.firedancer.io/radiance/pkg/fflags"
 	"go.firedancer.io/radiance/pkg/sbpf"
 	"go.firedancer.io/radiance/pkg/sbpf/loader"
 )
@@ -32,15 +34,15 @@ func TestExecute_Memo(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
+	require.NoError(t, program.Verify(registry))
 
 	interpreter := sbpf.NewInterpreter(program, opts)
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.NoError(t, err)
 
-	logs := opts.Context.(*Execution).Log.(*LogRecorder).Logs
+	logs := opts.Context.(*Execution).Log.(*LogCollector).Logs
 	assert.Equal(t, logs, []string{
 		`Program log: Memo (len 3): "Bla"`,
 	})
@@ -56,13 +58,12 @@ func TestInterpreter_Noop(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("log", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -73,12 +74,12 @@ func TestInterpreter_Noop(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	require.NoError(t, err)
 
 	assert.Equal(t, log.Logs, []string{
 		"Program log: entrypoint\x00",
-		"Program log: 0x1, 0x2, 0x3, 0x4, 0x5\n",
+		"Program log: 0x1, 0x2, 0x3, 0x4, 0x5",
 	})
 }
 
@@ -95,14 +96,13 @@ func TestInterpreter_Memcpy_Strings_Match(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_copy", SyscallMemcpy)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -113,7 +113,7 @@ func TestInterpreter_Memcpy_Strings_Match(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.Equal(t, log.Logs, []string{
 		"Program log: Strings matched after copy.",
 	})
@@ -134,14 +134,13 @@ func TestInterpreter_Memcpy_Do_Not_Match(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_copy", SyscallMemcpy)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -152,7 +151,7 @@ func TestInterpreter_Memcpy_Do_Not_Match(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.Equal(t, log.Logs, []string{
 		"Program log: Strings did not match after copy.",
 	})
@@ -172,14 +171,13 @@ func TestInterpreter_Memmove_Strings_Match(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_copy", SyscallMemmove)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -190,7 +188,7 @@ func TestInterpreter_Memmove_Strings_Match(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.Equal(t, log.Logs, []string{
 		"Program log: Strings matched after copy.",
 	})
@@ -211,14 +209,13 @@ func TestInterpreter_Memmove_Do_Not_Match(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_copy", SyscallMemmove)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -229,7 +226,7 @@ func TestInterpreter_Memmove_Do_Not_Match(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.Equal(t, log.Logs, []string{
 		"Program log: Strings did not match after copy.",
 	})
@@ -248,14 +245,13 @@ func TestInterpreter_Memcpy_Overlapping(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_copy", SyscallMemcpy)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -266,7 +262,7 @@ func TestInterpreter_Memcpy_Overlapping(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 
 	// expecting an error here because the src and dst are overlapping in the
 	// program being run.
@@ -286,14 +282,13 @@ func TestInterpreter_Memcmp_Matches(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_memcmp", SyscallMemcmp)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -304,7 +299,7 @@ func TestInterpreter_Memcmp_Matches(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	require.NoError(t, err)
 
 	assert.Equal(t, log.Logs, []string{
@@ -327,14 +322,13 @@ func TestInterpreter_Memcmp_Does_Not_Match(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	syscalls := sbpf.NewSyscallRegistry()
 	syscalls.Register("sol_log_", SyscallLog)
 	syscalls.Register("log_64", SyscallLog64)
 	syscalls.Register("my_memcmp", SyscallMemcmp)
+	require.NoError(t, program.Verify(syscalls))
 
-	var log LogRecorder
+	var log LogCollector
 
 	interpreter := sbpf.NewInterpreter(program, &sbpf.VMOpts{
 		HeapSize: 32 * 1024,
@@ -345,7 +339,7 @@ func TestInterpreter_Memcmp_Does_Not_Match(t *testing.T) {
 	})
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	require.NoError(t, err)
 
 	assert.Equal(t, log.Logs, []string{
@@ -370,19 +364,18 @@ func (e *executeCase) run(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, program)
 
-	require.NoError(t, program.Verify())
-
 	tx := TxContext{}
 	opts := tx.newVMOpts(&e.Params)
+	require.NoError(t, program.Verify(opts.Syscalls))
 	opts.Tracer = testLogger{t}
 
 	interpreter := sbpf.NewInterpreter(program, opts)
 	require.NotNil(t, interpreter)
 
-	err = interpreter.Run()
+	_, err = interpreter.Run()
 	assert.NoError(t, err)
 
-	logs := opts.Context.(*Execution).Log.(*LogRecorder).Logs
+	logs := opts.Context.(*Execution).Log.(*LogCollector).Logs
 	assert.Equal(t, logs, e.Logs)
 }
 
@@ -416,10 +409,30 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestTxContext_DirectMapping(t *testing.T) {
+	params := testParams()
+
+	// Account data is copied by default
+	var tx TxContext
+	opts := tx.newVMOpts(params)
+	assert.Equal(t, sbpf.MappingAligned, opts.Mapping)
+	assert.Nil(t, opts.InputRegions)
+	var flat bytes.Buffer
+	testParams().Serialize(&flat)
+	assert.Equal(t, flat.Bytes(), opts.Input)
+
+	tx.Features = new(fflags.Features).WithFeature(FeatureAccountDataDirectMapping)
+	opts = tx.newVMOpts(params)
+	assert.Equal(t, sbpf.MappingUnaligned, opts.Mapping)
+	assert.Nil(t, opts.Input)
+	require.Len(t, opts.InputRegions, 9)
+	assert.Equal(t, &params.Accounts[0].Data[0], &opts.InputRegions[1].Data[0])
+}
+
 type testLogger struct {
 	t *testing.T
 }
 
-func (t testLogger) Printf(format string, args ...any) {
-	t.t.Logf(format, args...)
+func (t testLogger) TraceIns(ev *sbpf.TraceEvent) {
+	t.t.Log(ev.String())
 }
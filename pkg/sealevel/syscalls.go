@@ -1,6 +1,12 @@
 package sealevel
 
 import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+
+	"go.firedancer.io/radiance/pkg/fflags"
 	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
@@ -8,18 +14,106 @@ var registry = Syscalls()
 
 // Syscalls creates a registry of all Sealevel syscalls.
 func Syscalls() sbpf.SyscallRegistry {
+	return newSyscalls(nil)
+}
+
+// syscallRegistries caches the registries returned by SyscallsFor, keyed by feature set.
+var syscallRegistries sync.Map // map[string]sbpf.SyscallRegistry
+
+// SyscallsFor returns the registry of Sealevel syscalls available under
+// the given feature set. A nil feature set enables all syscalls.
+//
+// The returned registry is shared and must not be modified.
+func SyscallsFor(features *fflags.Features) sbpf.SyscallRegistry {
+	key := features.Key()
+	if key == "" {
+		return registry
+	}
+	if reg, ok := syscallRegistries.Load(key); ok {
+		return reg.(sbpf.SyscallRegistry)
+	}
+	reg, _ := syscallRegistries.LoadOrStore(key, newSyscalls(features))
+	return reg.(sbpf.SyscallRegistry)
+}
+
+func newSyscalls(features *fflags.Features) sbpf.SyscallRegistry {
 	reg := sbpf.NewSyscallRegistry()
 	reg.Register("abort", SyscallAbort)
+	reg.Register("sol_panic_", SyscallPanic)
 	reg.Register("sol_log_", SyscallLog)
 	reg.Register("sol_log_64_", SyscallLog64)
 	reg.Register("sol_log_compute_units_", SyscallLogCUs)
 	reg.Register("sol_log_pubkey", SyscallLogPubkey)
+	reg.Register("sol_log_data", SyscallLogData)
 	reg.Register("sol_memcpy_", SyscallMemcpy)
 	reg.Register("sol_memmove_", SyscallMemmove)
 	reg.Register("sol_memcmp_", SyscallMemcmp)
+	reg.Register("sol_memset_", SyscallMemset)
+	reg.Register("sol_alloc_free_", SyscallAllocFree)
+	reg.Register("sol_sha256", SyscallSha256)
+	reg.Register("sol_keccak256", SyscallKeccak256)
+	reg.Register("sol_blake3", SyscallBlake3)
+	reg.Register("sol_secp256k1_recover", SyscallSecp256k1Recover)
+	reg.Register("sol_create_program_address", SyscallCreateProgramAddress)
+	reg.Register("sol_try_find_program_address", SyscallTryFindProgramAddress)
+	reg.Register("sol_get_clock_sysvar", SyscallGetClockSysvar)
+	reg.Register("sol_get_rent_sysvar", SyscallGetRentSysvar)
+	reg.Register("sol_get_epoch_schedule_sysvar", SyscallGetEpochScheduleSysvar)
+	if !features.HasFeature(FeatureDisableFeesSysvar) {
+		reg.Register("sol_get_fees_sysvar", SyscallGetFeesSysvar)
+	}
+	reg.Register("sol_set_return_data", SyscallSetReturnData)
+	reg.Register("sol_get_return_data", SyscallGetReturnData)
 	return reg
 }
 
 func syscallCtx(vm sbpf.VM) *Execution {
 	return vm.VMContext().(*Execution)
 }
+
+var ErrUnalignedPointer = errors.New("Unaligned pointer")
+
+// translateBytes returns the host memory of a byte slice in VM memory.
+// Empty slices are not translated.
+func translateBytes(vm sbpf.VM, addr, n uint64, write bool) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n > math.MaxUint32 {
+		return nil, sbpf.NewExcBadAccess(addr, math.MaxUint32, write, "slice too large")
+	}
+	return vm.Translate(addr, uint32(n), write)
+}
+
+// sliceDesc is the VM memory layout of a Rust &[u8].
+type sliceDesc struct {
+	addr uint64
+	len  uint64
+}
+
+const sliceDescSize = 16
+
+// readSliceDescs reads an array of n slice descriptors (&[&[u8]]) at addr.
+//
+// The array is translated before its alignment is checked, like in the validator.
+func readSliceDescs(vm sbpf.VM, addr, n uint64) ([]sliceDesc, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	if n > math.MaxUint32/sliceDescSize {
+		return nil, sbpf.NewExcBadAccess(addr, math.MaxUint32, false, "slice too large")
+	}
+	raw, err := translateBytes(vm, addr, n*sliceDescSize, false)
+	if err != nil {
+		return nil, err
+	}
+	if addr%8 != 0 {
+		return nil, ErrUnalignedPointer
+	}
+	descs := make([]sliceDesc, n)
+	for i := range descs {
+		descs[i].addr = binary.LittleEndian.Uint64(raw[i*sliceDescSize:])
+		descs[i].len = binary.LittleEndian.Uint64(raw[i*sliceDescSize+8:])
+	}
+	return descs, nil
+}
@@ -0,0 +1,95 @@
+package sealevel
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+var ErrTooManySlices = errors.New("Hashing too many sequences")
+
+// hasher is a hash function of a hashing syscall.
+type hasher struct {
+	name    string // as logged by the validator
+	syscall string // cost table key
+	new     func() hash.Hash
+}
+
+var (
+	sha256Hasher    = hasher{name: "Sha256", syscall: "sol_sha256", new: sha256.New}
+	keccak256Hasher = hasher{name: "Keccak256", syscall: "sol_keccak256", new: sha3.NewLegacyKeccak256}
+	blake3Hasher    = hasher{name: "Blake3", syscall: "sol_blake3", new: newBlake3}
+)
+
+func newBlake3() hash.Hash {
+	return blake3.New(32, nil)
+}
+
+// syscallHash hashes the concatenation of byte slices.
+//
+// Takes a pointer to an array of slice descriptors (&[&[u8]]), the number of slices,
+// and the address of the 32-byte digest output.
+func syscallHash(hasher hasher, vm sbpf.VM, valsAddr, valsLen, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	costs := vmCosts(vm)
+	if valsLen > costs.HashMaxSlices {
+		syscallCtx(vm).Log.Log(fmt.Sprintf("%s Hashing %d sequences in one syscall is over the limit %d",
+			hasher.name, valsLen, costs.HashMaxSlices))
+		return 0, cuIn, ErrTooManySlices
+	}
+	cuOut = cuIn - int(costs.Syscall(hasher.syscall))
+	if cuOut < 0 {
+		return
+	}
+
+	result, err := vm.Translate(resultAddr, 32, true)
+	if err != nil {
+		return
+	}
+	vals, err := readSliceDescs(vm, valsAddr, valsLen)
+	if err != nil {
+		return
+	}
+	h := hasher.new()
+	for _, val := range vals {
+		var data []byte
+		if data, err = translateBytes(vm, val.addr, val.len, false); err != nil {
+			return
+		}
+		cost := costs.HashByteCost * (val.len / 2)
+		if cost < costs.MemOpBase {
+			cost = costs.MemOpBase
+		}
+		if cuOut -= int(cost); cuOut < 0 {
+			return
+		}
+		h.Write(data)
+	}
+	h.Sum(result[:0])
+	return
+}
+
+// SyscallSha256Impl is the implementation of the sol_sha256 syscall.
+func SyscallSha256Impl(vm sbpf.VM, valsAddr, valsLen, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	return syscallHash(sha256Hasher, vm, valsAddr, valsLen, resultAddr, cuIn)
+}
+
+var SyscallSha256 = sbpf.SyscallFunc3(SyscallSha256Impl)
+
+// SyscallKeccak256Impl is the implementation of the sol_keccak256 syscall.
+func SyscallKeccak256Impl(vm sbpf.VM, valsAddr, valsLen, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	return syscallHash(keccak256Hasher, vm, valsAddr, valsLen, resultAddr, cuIn)
+}
+
+var SyscallKeccak256 = sbpf.SyscallFunc3(SyscallKeccak256Impl)
+
+// SyscallBlake3Impl is the implementation of the sol_blake3 syscall.
+func SyscallBlake3Impl(vm sbpf.VM, valsAddr, valsLen, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	return syscallHash(blake3Hasher, vm, valsAddr, valsLen, resultAddr, cuIn)
+}
+
+var SyscallBlake3 = sbpf.SyscallFunc3(SyscallBlake3Impl)
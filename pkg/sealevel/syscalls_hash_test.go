@@ -0,0 +1,109 @@
+package sealevel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// hashAsm hashes the slices "ab" and "c" into the input buffer.
+// Takes the syscall name, descriptor offset from r10 and slice count.
+const hashAsm = `
+	mov64 r6, r1
+	stb [r10-8], 0x61
+	stb [r10-7], 0x62
+	stb [r10-16], 0x63
+	mov64 r2, r10
+	sub64 r2, 8
+	stxdw [r10-48], r2
+	stdw [r10-40], 2
+	mov64 r2, r10
+	sub64 r2, 16
+	stxdw [r10-32], r2
+	stdw [r10-24], 1
+	mov64 r1, r10
+	sub64 r1, %d
+	mov64 r2, %d
+	mov64 r3, r6
+	call %s
+	exit
+`
+
+func runHashAsm(t *testing.T, syscall string, descOff int, n int) ([]byte, sbpf.Result, error) {
+	output, _, res, err := runHashAsmLog(t, syscall, descOff, n)
+	return output, res, err
+}
+
+// runHashAsmLog is like runHashAsm, but also returns the logs.
+func runHashAsmLog(t *testing.T, syscall string, descOff int, n int) ([]byte, []string, sbpf.Result, error) {
+	output := make([]byte, 32)
+	var log LogCollector
+	res, err := runSyscallAsm(t, fmt.Sprintf(hashAsm, descOff, n, syscall), &Execution{Log: &log}, output)
+	return output, log.Logs, res, err
+}
+
+func TestSyscallHash(t *testing.T) {
+	cases := []struct {
+		syscall string
+		empty   string
+		abc     string
+	}{
+		{
+			syscall: "sol_sha256",
+			empty:   "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			abc:     "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			syscall: "sol_keccak256",
+			empty:   "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+			abc:     "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		},
+		{
+			syscall: "sol_blake3",
+			empty:   "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+			abc:     "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.syscall, func(t *testing.T) {
+			digest, res, err := runHashAsm(t, c.syscall, 48, 2)
+			require.NoError(t, err)
+			assert.Equal(t, c.abc, hex.EncodeToString(digest))
+			// base cost plus MemOpBase per slice
+			assert.Equal(t, int(res.Instructions)+85+2*10, res.CUUsed)
+
+			digest, res, err = runHashAsm(t, c.syscall, 48, 0)
+			require.NoError(t, err)
+			assert.Equal(t, c.empty, hex.EncodeToString(digest))
+			assert.Equal(t, int(res.Instructions)+85, res.CUUsed)
+		})
+	}
+}
+
+func TestSyscallHash_Errors(t *testing.T) {
+	for syscall, name := range map[string]string{
+		"sol_sha256":    "Sha256",
+		"sol_keccak256": "Keccak256",
+		"sol_blake3":    "Blake3",
+	} {
+		_, logs, _, err := runHashAsmLog(t, syscall, 48, Sha256MaxSlices+1)
+		assert.ErrorIs(t, err, ErrTooManySlices)
+		assert.Equal(t, []string{name + " Hashing 20001 sequences in one syscall is over the limit 20000"}, logs)
+	}
+
+	_, _, err := runHashAsm(t, "sol_sha256", 47, 1)
+	assert.ErrorIs(t, err, ErrUnalignedPointer)
+
+	// Descriptors reaching into the stack gap
+	_, _, err = runHashAsm(t, "sol_sha256", 48, 300)
+	var exc sbpf.ExcBadAccess
+	assert.ErrorAs(t, err, &exc)
+
+	// Translation fails before alignment is checked
+	_, _, err = runHashAsm(t, "sol_sha256", 47, 300)
+	assert.ErrorAs(t, err, &exc)
+}
@@ -13,7 +13,7 @@ func SyscallLogImpl(vm sbpf.VM, ptr, strlen uint64, cuIn int) (r0 uint64, cuOut
 		cuOut = -1
 		return
 	}
-	cuOut = cu.ConsumeLowerBound(cuIn, CUSyscallBaseCost, int(strlen))
+	cuOut = cu.ConsumeLowerBound(cuIn, int(vmCosts(vm).Syscall("sol_log_")), int(strlen))
 	if cuOut < 0 {
 		return
 	}
@@ -29,12 +29,12 @@ func SyscallLogImpl(vm sbpf.VM, ptr, strlen uint64, cuIn int) (r0 uint64, cuOut
 var SyscallLog = sbpf.SyscallFunc2(SyscallLogImpl)
 
 func SyscallLog64Impl(vm sbpf.VM, r1, r2, r3, r4, r5 uint64, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = cuIn - CUSyscallBaseCost
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_log_64_"))
 	if cuOut < 0 {
 		return
 	}
 
-	msg := fmt.Sprintf("Program log: %#x, %#x, %#x, %#x, %#x\n", r1, r2, r3, r4, r5)
+	msg := fmt.Sprintf("Program log: %#x, %#x, %#x, %#x, %#x", r1, r2, r3, r4, r5)
 	syscallCtx(vm).Log.Log(msg)
 	return
 }
@@ -42,12 +42,12 @@ func SyscallLog64Impl(vm sbpf.VM, r1, r2, r3, r4, r5 uint64, cuIn int) (r0 uint6
 var SyscallLog64 = sbpf.SyscallFunc5(SyscallLog64Impl)
 
 func SyscallLogCUsImpl(vm sbpf.VM, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = cuIn - CUSyscallBaseCost
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_log_compute_units_"))
 	if cuOut < 0 {
 		return
 	}
 
-	msg := fmt.Sprintf("Program consumption: %d units remaining", cuIn)
+	msg := fmt.Sprintf("Program consumption: %d units remaining", cuOut)
 	syscallCtx(vm).Log.Log(msg)
 	return
 }
@@ -55,7 +55,7 @@ func SyscallLogCUsImpl(vm sbpf.VM, cuIn int) (r0 uint64, cuOut int, err error) {
 var SyscallLogCUs = sbpf.SyscallFunc0(SyscallLogCUsImpl)
 
 func SyscallLogPubkeyImpl(vm sbpf.VM, pubkeyAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = cuIn - CUSyscallBaseCost
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_log_pubkey"))
 	if cuOut < 0 {
 		return
 	}
@@ -71,3 +71,39 @@ func SyscallLogPubkeyImpl(vm sbpf.VM, pubkeyAddr uint64, cuIn int) (r0 uint64, c
 }
 
 var SyscallLogPubkey = sbpf.SyscallFunc1(SyscallLogPubkeyImpl)
+
+// SyscallLogDataImpl is the implementation of the sol_log_data syscall.
+//
+// Logs an array of byte slices (&[&[u8]]) as base64, separated by spaces.
+func SyscallLogDataImpl(vm sbpf.VM, addr, n uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cost := vmCosts(vm).Syscall("sol_log_data")
+	cuOut = cuIn - int(cost)
+	if cuOut < 0 {
+		return
+	}
+
+	descs, err := readSliceDescs(vm, addr, n)
+	if err != nil {
+		return
+	}
+	if cuOut -= int(cost * n); cuOut < 0 {
+		return
+	}
+	var total uint64
+	for _, desc := range descs {
+		total += desc.len
+	}
+	if cuOut -= int(total); cuOut < 0 {
+		return
+	}
+	fields := make([][]byte, len(descs))
+	for i, desc := range descs {
+		if fields[i], err = translateBytes(vm, desc.addr, desc.len, false); err != nil {
+			return
+		}
+	}
+	LogData(syscallCtx(vm).Log, fields)
+	return
+}
+
+var SyscallLogData = sbpf.SyscallFunc2(SyscallLogDataImpl)
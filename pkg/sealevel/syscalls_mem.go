@@ -4,16 +4,15 @@ import (
 	"errors"
 
 	"go.firedancer.io/radiance/pkg/sbpf"
-	"go.firedancer.io/radiance/pkg/sbpf/cu"
 )
 
 var (
 	ErrCopyOverlapping = errors.New("Overlapping copy")
 )
 
-func MemOpConsume(cuIn int, n uint64) int {
-	perBytesCost := n / CuCpiBytesPerUnit
-	return cu.ConsumeLowerBound(cuIn, CUMemOpBaseCost, int(perBytesCost))
+// MemOpConsume charges the cost of a memory syscall accessing n bytes.
+func MemOpConsume(vm sbpf.VM, cuIn int, n uint64) int {
+	return cuIn - int(vmCosts(vm).MemOp(n))
 }
 
 func isNonOverlapping(src, dst, n uint64) bool {
@@ -24,6 +23,15 @@ func isNonOverlapping(src, dst, n uint64) bool {
 	}
 }
 
+// isNonOverlappingRanges returns whether the memory ranges [a, a+aLen) and [b, b+bLen) are disjoint.
+func isNonOverlappingRanges(a, aLen, b, bLen uint64) bool {
+	if a > b {
+		return a-b >= bLen
+	} else {
+		return b-a >= aLen
+	}
+}
+
 func memmoveImplInternal(vm sbpf.VM, dst, src, n uint64) (err error) {
 	srcBuf := make([]byte, n)
 	err = vm.Read(src, srcBuf)
@@ -37,7 +45,7 @@ func memmoveImplInternal(vm sbpf.VM, dst, src, n uint64) (err error) {
 // SyscallMemcpyImpl is the implementation of the memcpy (sol_memcpy_) syscall.
 // Overlapping src and dst for a given n bytes to be copied results in an error being returned.
 func SyscallMemcpyImpl(vm sbpf.VM, dst, src, n uint64, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = MemOpConsume(cuIn, n)
+	cuOut = MemOpConsume(vm, cuIn, n)
 	if cuOut < 0 {
 		return
 	}
@@ -56,7 +64,7 @@ var SyscallMemcpy = sbpf.SyscallFunc3(SyscallMemcpyImpl)
 
 // SyscallMemmoveImpl is the implementation for the memmove (sol_memmove_) syscall.
 func SyscallMemmoveImpl(vm sbpf.VM, dst, src, n uint64, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = MemOpConsume(cuIn, n)
+	cuOut = MemOpConsume(vm, cuIn, n)
 	if cuOut < 0 {
 		return
 	}
@@ -68,7 +76,7 @@ var SyscallMemmove = sbpf.SyscallFunc3(SyscallMemmoveImpl)
 
 // SyscallMemcmpImpl is the implementation for the memcmp (sol_memcmp_) syscall.
 func SyscallMemcmpImpl(vm sbpf.VM, addr1, addr2, n, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
-	cuOut = MemOpConsume(cuIn, n)
+	cuOut = MemOpConsume(vm, cuIn, n)
 	if cuOut < 0 {
 		return
 	}
@@ -96,3 +104,22 @@ func SyscallMemcmpImpl(vm sbpf.VM, addr1, addr2, n, resultAddr uint64, cuIn int)
 }
 
 var SyscallMemcmp = sbpf.SyscallFunc4(SyscallMemcmpImpl)
+
+// SyscallMemsetImpl is the implementation for the memset (sol_memset_) syscall.
+func SyscallMemsetImpl(vm sbpf.VM, dst, c, n uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cuOut = MemOpConsume(vm, cuIn, n)
+	if cuOut < 0 {
+		return
+	}
+
+	buf, err := translateBytes(vm, dst, n, true)
+	if err != nil {
+		return
+	}
+	for i := range buf {
+		buf[i] = byte(c)
+	}
+	return
+}
+
+var SyscallMemset = sbpf.SyscallFunc3(SyscallMemsetImpl)
@@ -0,0 +1,31 @@
+package sealevel
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+func TestSyscallMemset(t *testing.T) {
+	program, err := sbpf.Assemble("exit")
+	require.NoError(t, err)
+	input := make([]byte, 8)
+	vm := sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		Syscalls: registry,
+		Costs:    DefaultCosts(),
+		MaxCU:    math.MaxInt64,
+		Input:    input,
+	})
+
+	_, _, err = SyscallMemsetImpl(vm, sbpf.VaddrInput, 0xaa, 4, math.MaxInt64)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xaa, 0xaa, 0xaa, 0xaa, 0, 0, 0, 0}, input)
+
+	// Lengths must not be truncated to 32 bits
+	_, _, err = SyscallMemsetImpl(vm, sbpf.VaddrInput, 0xbb, 1<<32+1, math.MaxInt64)
+	assert.ErrorAs(t, err, new(sbpf.ExcBadAccess))
+	assert.Equal(t, byte(0), input[4])
+}
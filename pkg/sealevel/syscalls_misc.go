@@ -2,13 +2,48 @@ package sealevel
 
 import (
 	"errors"
+	"fmt"
 
 	"go.firedancer.io/radiance/pkg/sbpf"
 )
 
+// ErrAbort is returned by the abort syscall.
+var ErrAbort = errors.New("SBF program panicked")
+
 func SyscallAbortImpl(_ sbpf.VM, _ int) (r0 uint64, cuOut int, err error) {
-	err = errors.New("aborted")
+	err = ErrAbort
 	return
 }
 
 var SyscallAbort = sbpf.SyscallFunc0(SyscallAbortImpl)
+
+// PanicError is returned by the panic (sol_panic_) syscall.
+type PanicError struct {
+	File   string
+	Line   uint64
+	Column uint64
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("SBF program Panicked in %s at %d:%d", e.File, e.Line, e.Column)
+}
+
+// SyscallPanicImpl is the implementation for the panic (sol_panic_) syscall.
+//
+// Charges one compute unit per byte of the file name.
+func SyscallPanicImpl(vm sbpf.VM, file, n, line, column uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	if n > uint64(cuIn) {
+		cuOut = -1
+		return
+	}
+	cuOut = cuIn - int(n)
+
+	buf, err := translateBytes(vm, file, n, false)
+	if err != nil {
+		return
+	}
+	err = &PanicError{File: string(buf), Line: line, Column: column}
+	return
+}
+
+var SyscallPanic = sbpf.SyscallFunc4(SyscallPanicImpl)
@@ -0,0 +1,32 @@
+package sealevel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+func TestSyscallPanic(t *testing.T) {
+	_, err := runSyscallAsm(t, `
+		lddw r1, 0x400000000
+		mov64 r2, 10
+		mov64 r3, 7
+		mov64 r4, 3
+		call sol_panic_
+		exit
+	`, new(Execution), []byte("src/lib.rs"))
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, &PanicError{File: "src/lib.rs", Line: 7, Column: 3}, panicErr)
+
+	// A file name length wrapping to a positive CU count must not be allocated
+	_, err = runSyscallAsm(t, `
+		lddw r1, 0x400000000
+		lddw r2, -1
+		call sol_panic_
+		exit
+	`, new(Execution), []byte("src/lib.rs"))
+	assert.ErrorIs(t, err, sbpf.ExcOutOfCU)
+}
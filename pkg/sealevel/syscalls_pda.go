@@ -0,0 +1,100 @@
+package sealevel
+
+import (
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/solana"
+)
+
+// translateProgramAddressInputs reads the seeds and program ID of a program address syscall.
+//
+// Like the validator, the seed descriptors are translated before the seed count is checked,
+// and the length of each seed is checked before its data is translated.
+func translateProgramAddressInputs(vm sbpf.VM, seedsAddr, seedsLen, programIDAddr uint64) (seeds [][]byte, programID solana.Address, err error) {
+	descs, err := readSliceDescs(vm, seedsAddr, seedsLen)
+	if err != nil {
+		return
+	}
+	if len(descs) > solana.MaxSeeds {
+		return nil, programID, solana.ErrMaxSeedLengthExceeded
+	}
+	seeds = make([][]byte, len(descs))
+	for i, desc := range descs {
+		if desc.len > solana.MaxSeedLen {
+			return nil, programID, solana.ErrMaxSeedLengthExceeded
+		}
+		if seeds[i], err = translateBytes(vm, desc.addr, desc.len, false); err != nil {
+			return
+		}
+	}
+	if err = vm.Read(programIDAddr, programID[:]); err != nil {
+		return
+	}
+	return
+}
+
+// SyscallCreateProgramAddressImpl is the implementation of the sol_create_program_address syscall.
+//
+// Returns 1 if the derived address lies on the curve.
+func SyscallCreateProgramAddressImpl(vm sbpf.VM, seedsAddr, seedsLen, programIDAddr, addressAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_create_program_address"))
+	if cuOut < 0 {
+		return
+	}
+
+	seeds, programID, err := translateProgramAddressInputs(vm, seedsAddr, seedsLen, programIDAddr)
+	if err != nil {
+		return
+	}
+	addr, deriveErr := solana.CreateProgramAddress(seeds, programID)
+	if deriveErr != nil {
+		return 1, cuOut, nil
+	}
+	err = vm.Write(addressAddr, addr[:])
+	return
+}
+
+var SyscallCreateProgramAddress = sbpf.SyscallFunc4(SyscallCreateProgramAddressImpl)
+
+// SyscallTryFindProgramAddressImpl is the implementation of the sol_try_find_program_address syscall.
+//
+// Tries bump seeds from 255 down to 1, charging the base cost again for every failed attempt.
+// Writes the first valid address and its bump seed, or returns 1 if there is none.
+func SyscallTryFindProgramAddressImpl(vm sbpf.VM, seedsAddr, seedsLen, programIDAddr, addressAddr, bumpSeedAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cost := int(vmCosts(vm).Syscall("sol_try_find_program_address"))
+	cuOut = cuIn - cost
+	if cuOut < 0 {
+		return
+	}
+
+	seeds, programID, err := translateProgramAddressInputs(vm, seedsAddr, seedsLen, programIDAddr)
+	if err != nil {
+		return
+	}
+	var bumpSeed [1]byte
+	seeds = append(seeds, bumpSeed[:])
+	for bump := 255; bump > 0; bump-- {
+		bumpSeed[0] = uint8(bump)
+		addr, deriveErr := solana.CreateProgramAddress(seeds, programID)
+		if deriveErr == nil {
+			var bumpOut, addrOut []byte
+			if bumpOut, err = vm.Translate(bumpSeedAddr, 1, true); err != nil {
+				return
+			}
+			if addrOut, err = vm.Translate(addressAddr, 32, true); err != nil {
+				return
+			}
+			if bumpSeedAddr >= addressAddr && bumpSeedAddr-addressAddr < 32 {
+				return r0, cuOut, ErrCopyOverlapping
+			}
+			bumpOut[0] = bumpSeed[0]
+			copy(addrOut, addr[:])
+			return 0, cuOut, nil
+		}
+		if cuOut -= cost; cuOut < 0 {
+			return
+		}
+	}
+	return 1, cuOut, nil
+}
+
+var SyscallTryFindProgramAddress = sbpf.SyscallFunc5(SyscallTryFindProgramAddressImpl)
@@ -0,0 +1,108 @@
+package sealevel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+	"go.firedancer.io/radiance/pkg/solana"
+)
+
+// pdaAsm derives a program address from the seeds "abc" and the byte at
+// input[64], using the program ID at input[0:32].
+// The address is written to input[32:64] and the bump seed to input[64].
+// Takes the syscall name, the seed count and the length of the first seed.
+const pdaAsm = `
+	mov64 r6, r1
+	stb [r10-8], 0x61
+	stb [r10-7], 0x62
+	stb [r10-6], 0x63
+	mov64 r2, r10
+	sub64 r2, 8
+	stxdw [r10-48], r2
+	stdw [r10-40], %[3]d
+	mov64 r2, r6
+	add64 r2, 64
+	stxdw [r10-32], r2
+	stdw [r10-24], 1
+	mov64 r1, r10
+	sub64 r1, 48
+	mov64 r2, %[2]d
+	mov64 r3, r6
+	mov64 r4, r6
+	add64 r4, 32
+	mov64 r5, r6
+	add64 r5, 64
+	call %[1]s
+	exit
+`
+
+func runPDAAsm(t *testing.T, syscall string, programID solana.Address, bump byte, nSeeds, seedLen int) (solana.Address, byte, sbpf.Result, error) {
+	input := make([]byte, 65)
+	copy(input, programID[:])
+	input[64] = bump
+	res, err := runSyscallAsm(t, fmt.Sprintf(pdaAsm, syscall, nSeeds, seedLen), &Execution{}, input)
+	return *(*solana.Address)(input[32:64]), input[64], res, err
+}
+
+func TestSyscallTryFindProgramAddress(t *testing.T) {
+	programID := solana.MustAddress("BPFLoaderUpgradeab1e11111111111111111111111")
+	want, wantBump, ok := solana.TryFindProgramAddress([][]byte{[]byte("abc")}, programID)
+	require.True(t, ok)
+
+	addr, bump, res, err := runPDAAsm(t, "sol_try_find_program_address", programID, 0, 1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), res.R0)
+	assert.Equal(t, want, addr)
+	assert.Equal(t, wantBump, bump)
+	// base cost plus one charge per failed bump seed
+	attempts := 1 + 255 - int(wantBump)
+	assert.Equal(t, int(res.Instructions)+attempts*CUCreateProgramAddressCost, res.CUUsed)
+}
+
+func TestSyscallCreateProgramAddress(t *testing.T) {
+	programID := solana.MustAddress("BPFLoaderUpgradeab1e11111111111111111111111")
+	seed := []byte("abc")
+
+	for bump := 255; bump >= 250; bump-- {
+		want, wantErr := solana.CreateProgramAddress([][]byte{seed, {byte(bump)}}, programID)
+		addr, _, res, err := runPDAAsm(t, "sol_create_program_address", programID, byte(bump), 2, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int(res.Instructions)+CUCreateProgramAddressCost, res.CUUsed)
+		if wantErr != nil {
+			assert.Equal(t, uint64(1), res.R0)
+			assert.Equal(t, solana.Address{}, addr)
+		} else {
+			assert.Equal(t, uint64(0), res.R0)
+			assert.Equal(t, want, addr)
+		}
+	}
+}
+
+func TestSyscallProgramAddress_Errors(t *testing.T) {
+	programID := solana.MustAddress("BPFLoaderUpgradeab1e11111111111111111111111")
+	for _, syscall := range []string{"sol_create_program_address", "sol_try_find_program_address"} {
+		// Descriptors past the stack frame are translated before the seed count is checked
+		_, _, _, err := runPDAAsm(t, syscall, programID, 0, solana.MaxSeeds+1, 3)
+		var exc sbpf.ExcBadAccess
+		assert.ErrorAs(t, err, &exc)
+
+		// Empty seeds read from the input
+		input := make([]byte, (solana.MaxSeeds+1)*16)
+		_, err = runSyscallAsm(t, fmt.Sprintf(`
+			mov64 r2, %d
+			mov64 r3, r1
+			mov64 r4, r1
+			mov64 r5, r1
+			call %s
+			exit
+		`, solana.MaxSeeds+1, syscall), &Execution{}, input)
+		assert.ErrorIs(t, err, solana.ErrMaxSeedLengthExceeded)
+
+		// Seed lengths are checked before the seed is translated
+		_, _, _, err = runPDAAsm(t, syscall, programID, 0, 1, solana.MaxSeedLen+1)
+		assert.ErrorIs(t, err, solana.ErrMaxSeedLengthExceeded)
+	}
+}
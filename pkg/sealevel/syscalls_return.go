@@ -0,0 +1,84 @@
+package sealevel
+
+import (
+	"errors"
+	"fmt"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// MaxReturnData is the maximum size of return data set by a program.
+const MaxReturnData = 1024
+
+var ErrReturnDataTooLarge = errors.New("Return data too large")
+
+// SyscallSetReturnDataImpl is the implementation of the sol_set_return_data syscall.
+//
+// Replaces the return data of the transaction, recording the current program as its setter.
+func SyscallSetReturnDataImpl(vm sbpf.VM, addr, n uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	costs := vmCosts(vm)
+	cost := costs.Syscall("sol_set_return_data")
+	if costs.CPIBytesPerUnit != 0 {
+		cost += n / costs.CPIBytesPerUnit
+	}
+	cuOut = cuIn - int(cost)
+	if cuOut < 0 {
+		return
+	}
+
+	if n > MaxReturnData {
+		return r0, cuOut, fmt.Errorf("%w (%d > %d)", ErrReturnDataTooLarge, n, MaxReturnData)
+	}
+	data, err := translateBytes(vm, addr, n, false)
+	if err != nil {
+		return
+	}
+	execution := syscallCtx(vm)
+	execution.ReturnData = ReturnData{
+		ProgramID: execution.ProgramID,
+		Data:      append([]byte(nil), data...),
+	}
+	return
+}
+
+var SyscallSetReturnData = sbpf.SyscallFunc2(SyscallSetReturnDataImpl)
+
+// SyscallGetReturnDataImpl is the implementation of the sol_get_return_data syscall.
+//
+// Copies up to n bytes of return data to addr and the ID of the program that set it to programIDAddr.
+// Returns the full length of the return data.
+func SyscallGetReturnDataImpl(vm sbpf.VM, addr, n, programIDAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	costs := vmCosts(vm)
+	cuOut = cuIn - int(costs.Syscall("sol_get_return_data"))
+	if cuOut < 0 {
+		return
+	}
+
+	returnData := &syscallCtx(vm).ReturnData
+	if uint64(len(returnData.Data)) < n {
+		n = uint64(len(returnData.Data))
+	}
+	if n != 0 {
+		if costs.CPIBytesPerUnit != 0 {
+			cuOut -= int((n + 32) / costs.CPIBytesPerUnit)
+			if cuOut < 0 {
+				return
+			}
+		}
+		var data, programID []byte
+		if data, err = vm.Translate(addr, uint32(n), true); err != nil {
+			return
+		}
+		if programID, err = vm.Translate(programIDAddr, 32, true); err != nil {
+			return
+		}
+		if !isNonOverlappingRanges(addr, n, programIDAddr, 32) {
+			return r0, cuOut, ErrCopyOverlapping
+		}
+		copy(data, returnData.Data)
+		copy(programID, returnData.ProgramID[:])
+	}
+	return uint64(len(returnData.Data)), cuOut, nil
+}
+
+var SyscallGetReturnData = sbpf.SyscallFunc3(SyscallGetReturnDataImpl)
@@ -0,0 +1,105 @@
+package sealevel
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// returnDataAsm sets "abc" as return data, then reads it back into the input
+// buffer, with the program ID at input[32:64].
+// Takes the set length, the get length and the program ID offset.
+const returnDataAsm = `
+	mov64 r6, r1
+	stb [r10-8], 0x61
+	stb [r10-7], 0x62
+	stb [r10-6], 0x63
+	mov64 r1, r10
+	sub64 r1, 8
+	mov64 r2, %d
+	call sol_set_return_data
+	mov64 r1, r6
+	mov64 r2, %d
+	mov64 r3, r6
+	add64 r3, %d
+	call sol_get_return_data
+	exit
+`
+
+var testProgramID = solana.MustPublicKeyFromBase58("BPFLoaderUpgradeab1e11111111111111111111111")
+
+func runReturnDataAsm(t *testing.T, setLen, getLen, programIDOff int) ([]byte, *Execution, sbpf.Result, error) {
+	input := make([]byte, 64)
+	execution := &Execution{ProgramID: testProgramID}
+	res, err := runSyscallAsm(t, fmt.Sprintf(returnDataAsm, setLen, getLen, programIDOff), execution, input)
+	return input, execution, res, err
+}
+
+func TestSyscallReturnData(t *testing.T) {
+	input, execution, res, err := runReturnDataAsm(t, 3, 3, 32)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), res.R0)
+	assert.Equal(t, []byte("abc"), input[:3])
+	assert.Equal(t, testProgramID[:], input[32:])
+	assert.Equal(t, ReturnData{ProgramID: testProgramID, Data: []byte("abc")}, execution.ReturnData)
+	assert.Equal(t, int(res.Instructions)+2*CUSyscallBaseCost, res.CUUsed)
+
+	// Short reads return the full length
+	input, _, res, err = runReturnDataAsm(t, 3, 2, 32)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), res.R0)
+	assert.Equal(t, []byte("ab\x00"), input[:3])
+
+	// Empty reads do not write the program ID
+	input, _, res, err = runReturnDataAsm(t, 3, 0, 32)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), res.R0)
+	assert.Equal(t, make([]byte, 64), input)
+
+	// Clearing return data
+	_, execution, res, err = runReturnDataAsm(t, 0, 3, 32)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), res.R0)
+	assert.Empty(t, execution.ReturnData.Data)
+}
+
+func TestSyscallReturnData_Errors(t *testing.T) {
+	_, _, _, err := runReturnDataAsm(t, MaxReturnData+1, 3, 32)
+	assert.ErrorIs(t, err, ErrReturnDataTooLarge)
+
+	_, _, _, err = runReturnDataAsm(t, 3, 3, 2)
+	assert.ErrorIs(t, err, ErrCopyOverlapping)
+}
+
+func TestTxContext_Execute_ReturnData(t *testing.T) {
+	program, err := sbpf.Assemble(fmt.Sprintf(returnDataAsm, 3, 0, 0))
+	require.NoError(t, err)
+
+	var tx TxContext
+	res, err := tx.Execute(program, &Params{ProgramID: testProgramID})
+	// Returns the length of the return data as a custom error
+	assert.EqualError(t, err, "custom program error: 0x3")
+	assert.Equal(t, uint64(3), res.R0)
+	assert.Equal(t, []byte("abc"), res.ReturnData.Data)
+	assert.Equal(t, res.ReturnData, tx.ReturnData)
+	assert.Equal(t, []string{
+		"Program BPFLoaderUpgradeab1e11111111111111111111111 invoke [1]",
+		fmt.Sprintf("Program BPFLoaderUpgradeab1e11111111111111111111111 consumed %d of 1400000 compute units", res.CUUsed),
+		"Program return: BPFLoaderUpgradeab1e11111111111111111111111 YWJj",
+		"Program BPFLoaderUpgradeab1e11111111111111111111111 failed: custom program error: 0x3",
+	}, tx.Log.Logs)
+}
+
+func TestLogReturnData(t *testing.T) {
+	var log LogCollector
+	LogReturnData(&log, testProgramID, &ReturnData{})
+	assert.Empty(t, log.Logs)
+
+	// Names the executing program, not the one that set the return data
+	LogReturnData(&log, testProgramID, &ReturnData{ProgramID: solana.SystemProgramID, Data: []byte("abc")})
+	assert.Equal(t, []string{"Program return: BPFLoaderUpgradeab1e11111111111111111111111 YWJj"}, log.Logs)
+}
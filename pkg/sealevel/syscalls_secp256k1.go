@@ -0,0 +1,59 @@
+package sealevel
+
+import (
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// Error codes returned by sol_secp256k1_recover.
+const (
+	Secp256k1RecoverSuccess           = 0
+	Secp256k1RecoverInvalidHash       = 1
+	Secp256k1RecoverInvalidRecoveryID = 2
+	Secp256k1RecoverInvalidSignature  = 3
+)
+
+const (
+	secp256k1SignatureLen = 64
+	secp256k1PubkeyLen    = 64
+)
+
+// SyscallSecp256k1RecoverImpl is the implementation of the sol_secp256k1_recover syscall.
+//
+// Recovers the uncompressed public key (without the 0x04 prefix) that signed
+// the 32-byte hash with the given recovery ID and 64-byte signature (r || s).
+// Like the reference implementation, high-S signatures are accepted.
+func SyscallSecp256k1RecoverImpl(vm sbpf.VM, hashAddr, recoveryID, signatureAddr, resultAddr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	cuOut = cuIn - int(vmCosts(vm).Syscall("sol_secp256k1_recover"))
+	if cuOut < 0 {
+		return
+	}
+
+	hash, err := vm.Translate(hashAddr, 32, false)
+	if err != nil {
+		return
+	}
+	signature, err := vm.Translate(signatureAddr, secp256k1SignatureLen, false)
+	if err != nil {
+		return
+	}
+	result, err := vm.Translate(resultAddr, secp256k1PubkeyLen, true)
+	if err != nil {
+		return
+	}
+
+	if recoveryID >= 4 {
+		return Secp256k1RecoverInvalidRecoveryID, cuOut, nil
+	}
+	var compact [1 + secp256k1SignatureLen]byte
+	compact[0] = 27 + byte(recoveryID) // uncompressed key magic
+	copy(compact[1:], signature)
+	pubkey, _, recoverErr := ecdsa.RecoverCompact(compact[:], hash)
+	if recoverErr != nil {
+		return Secp256k1RecoverInvalidSignature, cuOut, nil
+	}
+	copy(result, pubkey.SerializeUncompressed()[1:])
+	return Secp256k1RecoverSuccess, cuOut, nil
+}
+
+var SyscallSecp256k1Recover = sbpf.SyscallFunc4(SyscallSecp256k1RecoverImpl)
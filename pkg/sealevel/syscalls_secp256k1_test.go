@@ -0,0 +1,74 @@
+package sealevel
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runSecp256k1Recover calls sol_secp256k1_recover with the hash, signature and
+// result buffer placed in the input region.
+func runSecp256k1Recover(t *testing.T, hash []byte, recoveryID uint64, sig []byte) (uint64, []byte) {
+	input := make([]byte, 160)
+	copy(input, hash)
+	copy(input[32:], sig)
+	res, err := runSyscallAsm(t, fmt.Sprintf(`
+		mov64 r3, r1
+		add64 r3, 32
+		mov64 r4, r1
+		add64 r4, 96
+		mov64 r2, %d
+		call sol_secp256k1_recover
+		exit
+	`, recoveryID), &Execution{}, input)
+	require.NoError(t, err)
+	return res.R0, input[96:]
+}
+
+func TestSyscallSecp256k1Recover(t *testing.T) {
+	key := secp256k1.PrivKeyFromBytes([]byte("radiance secp256k1 recover test!"))
+	hash := sha256.Sum256([]byte("hello"))
+	compact := ecdsa.SignCompact(key, hash[:], false)
+	recoveryID := uint64(compact[0] - 27)
+	sig := compact[1:]
+	pubkey := key.PubKey().SerializeUncompressed()[1:]
+
+	code, result := runSecp256k1Recover(t, hash[:], recoveryID, sig)
+	assert.Equal(t, uint64(Secp256k1RecoverSuccess), code)
+	assert.Equal(t, pubkey, result)
+
+	// High-S signatures are accepted and recover the same key
+	s := new(big.Int).SetBytes(sig[32:])
+	s.Sub(secp256k1.S256().N, s)
+	highS := append([]byte(nil), sig[:32]...)
+	highS = append(highS, s.FillBytes(make([]byte, 32))...)
+	code, result = runSecp256k1Recover(t, hash[:], recoveryID^1, highS)
+	assert.Equal(t, uint64(Secp256k1RecoverSuccess), code)
+	assert.Equal(t, pubkey, result)
+
+	// Wrong recovery ID yields a different key
+	code, result = runSecp256k1Recover(t, hash[:], recoveryID^1, sig)
+	if code == Secp256k1RecoverSuccess {
+		assert.NotEqual(t, pubkey, result)
+	}
+
+	code, _ = runSecp256k1Recover(t, hash[:], 4, sig)
+	assert.Equal(t, uint64(Secp256k1RecoverInvalidRecoveryID), code)
+	code, _ = runSecp256k1Recover(t, hash[:], 256, sig)
+	assert.Equal(t, uint64(Secp256k1RecoverInvalidRecoveryID), code)
+
+	zeroR := append(make([]byte, 32), sig[32:]...)
+	code, _ = runSecp256k1Recover(t, hash[:], recoveryID, zeroR)
+	assert.Equal(t, uint64(Secp256k1RecoverInvalidSignature), code)
+
+	overflowS := append(append([]byte(nil), sig[:32]...), secp256k1.S256().N.Bytes()...)
+	code, result = runSecp256k1Recover(t, hash[:], recoveryID, overflowS)
+	assert.Equal(t, uint64(Secp256k1RecoverInvalidSignature), code)
+	assert.Equal(t, make([]byte, 64), result)
+}
@@ -0,0 +1,70 @@
+package sealevel
+
+import (
+	"errors"
+
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+var ErrUnsupportedSysvar = errors.New("Unsupported sysvar")
+
+// syscallGetSysvar writes a sysvar of the given size to addr.
+//
+// Charges the base cost plus one unit per byte before translating,
+// and translates before checking alignment, like the validator.
+// Fails with ErrUnsupportedSysvar if the sysvar is not cached.
+func syscallGetSysvar(vm sbpf.VM, name string, addr uint64, size uint32, available bool, put func(b []byte), cuIn int) (r0 uint64, cuOut int, err error) {
+	cuOut = cuIn - int(vmCosts(vm).Syscall(name)) - int(size)
+	if cuOut < 0 {
+		return
+	}
+
+	b, err := vm.Translate(addr, size, true)
+	if err != nil {
+		return
+	}
+	if addr%sysvarAlign != 0 {
+		return r0, cuOut, ErrUnalignedPointer
+	}
+	if !available {
+		return r0, cuOut, ErrUnsupportedSysvar
+	}
+	put(b)
+	return
+}
+
+// SyscallGetClockSysvarImpl is the implementation of the sol_get_clock_sysvar syscall.
+func SyscallGetClockSysvarImpl(vm sbpf.VM, addr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	clock := syscallCtx(vm).Sysvars.Clock
+	return syscallGetSysvar(vm, "sol_get_clock_sysvar", addr, ClockSize, clock != nil,
+		func(b []byte) { putClock(b, clock) }, cuIn)
+}
+
+var SyscallGetClockSysvar = sbpf.SyscallFunc1(SyscallGetClockSysvarImpl)
+
+// SyscallGetRentSysvarImpl is the implementation of the sol_get_rent_sysvar syscall.
+func SyscallGetRentSysvarImpl(vm sbpf.VM, addr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	rent := syscallCtx(vm).Sysvars.Rent
+	return syscallGetSysvar(vm, "sol_get_rent_sysvar", addr, RentSize, rent != nil,
+		func(b []byte) { putRent(b, rent) }, cuIn)
+}
+
+var SyscallGetRentSysvar = sbpf.SyscallFunc1(SyscallGetRentSysvarImpl)
+
+// SyscallGetEpochScheduleSysvarImpl is the implementation of the sol_get_epoch_schedule_sysvar syscall.
+func SyscallGetEpochScheduleSysvarImpl(vm sbpf.VM, addr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	schedule := syscallCtx(vm).Sysvars.EpochSchedule
+	return syscallGetSysvar(vm, "sol_get_epoch_schedule_sysvar", addr, EpochScheduleSize, schedule != nil,
+		func(b []byte) { putEpochSchedule(b, schedule) }, cuIn)
+}
+
+var SyscallGetEpochScheduleSysvar = sbpf.SyscallFunc1(SyscallGetEpochScheduleSysvarImpl)
+
+// SyscallGetFeesSysvarImpl is the implementation of the sol_get_fees_sysvar syscall.
+func SyscallGetFeesSysvarImpl(vm sbpf.VM, addr uint64, cuIn int) (r0 uint64, cuOut int, err error) {
+	fees := syscallCtx(vm).Sysvars.Fees
+	return syscallGetSysvar(vm, "sol_get_fees_sysvar", addr, FeesSize, fees != nil,
+		func(b []byte) { putFees(b, fees) }, cuIn)
+}
+
+var SyscallGetFeesSysvar = sbpf.SyscallFunc1(SyscallGetFeesSysvarImpl)
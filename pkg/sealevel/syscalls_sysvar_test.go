@@ -0,0 +1,103 @@
+package sealevel
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/runtime"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+var testSysvars = SysvarCache{
+	Clock: &runtime.Clock{
+		Slot:                0x0102,
+		EpochStartTimestamp: -2,
+		Epoch:               0x03,
+		LeaderScheduleEpoch: 0x04,
+		UnixTimestamp:       0x05,
+	},
+	Rent: &runtime.RentParams{
+		LamportsPerByteYear: 3480,
+		ExemptionThreshold:  2.0,
+		BurnPercent:         50,
+	},
+	EpochSchedule: &runtime.EpochSchedule{
+		SlotPerEpoch:             432000,
+		LeaderScheduleSlotOffset: 432000,
+		Warmup:                   true,
+		FirstNormalEpoch:         14,
+		FirstNormalSlot:          524256,
+	},
+	Fees: &runtime.Fees{LamportsPerSignature: 5000},
+}
+
+// runSysvarAsm calls a sysvar getter with the input region at the given offset.
+func runSysvarAsm(t *testing.T, syscall string, off int, sysvars SysvarCache) ([]byte, sbpf.Result, error) {
+	input := make([]byte, 48)
+	for i := range input {
+		input[i] = 0xff
+	}
+	res, err := runSyscallAsm(t, fmt.Sprintf(`
+		add64 r1, %d
+		call %s
+		exit
+	`, off, syscall), &Execution{Sysvars: sysvars}, input)
+	return input, res, err
+}
+
+func TestSyscallGetSysvar(t *testing.T) {
+	cases := []struct {
+		syscall string
+		size    int
+		layout  string
+	}{
+		{
+			syscall: "sol_get_clock_sysvar",
+			size:    ClockSize,
+			layout: "0201000000000000" + "feffffffffffffff" + "0300000000000000" +
+				"0400000000000000" + "0500000000000000",
+		},
+		{
+			syscall: "sol_get_rent_sysvar",
+			size:    RentSize,
+			layout:  "980d000000000000" + "0000000000000040" + "3200000000000000",
+		},
+		{
+			syscall: "sol_get_epoch_schedule_sysvar",
+			size:    EpochScheduleSize,
+			layout: "8097060000000000" + "8097060000000000" + "0100000000000000" +
+				"0e00000000000000" + "e0ff070000000000",
+		},
+		{
+			syscall: "sol_get_fees_sysvar",
+			size:    FeesSize,
+			layout:  "8813000000000000",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.syscall, func(t *testing.T) {
+			out, res, err := runSysvarAsm(t, c.syscall, 0, testSysvars)
+			require.NoError(t, err)
+			assert.Equal(t, c.layout, hex.EncodeToString(out[:c.size]))
+			assert.Equal(t, int(res.Instructions)+CUSysvarBaseCost+c.size, res.CUUsed)
+
+			_, _, err = runSysvarAsm(t, c.syscall, 4, testSysvars)
+			assert.ErrorIs(t, err, ErrUnalignedPointer)
+
+			_, _, err = runSysvarAsm(t, c.syscall, 0, SysvarCache{})
+			assert.ErrorIs(t, err, ErrUnsupportedSysvar)
+
+			// Writing past the end of the input region
+			_, _, err = runSysvarAsm(t, c.syscall, 48-c.size+8, testSysvars)
+			var exc sbpf.ExcBadAccess
+			assert.ErrorAs(t, err, &exc)
+
+			// Translation fails before alignment is checked
+			_, _, err = runSysvarAsm(t, c.syscall, 48-c.size+4, testSysvars)
+			assert.ErrorAs(t, err, &exc)
+		})
+	}
+}
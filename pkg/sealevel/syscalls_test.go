@@ -0,0 +1,46 @@
+package sealevel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.firedancer.io/radiance/pkg/fflags"
+	"go.firedancer.io/radiance/pkg/sbpf"
+)
+
+// runSyscallAsm assembles, verifies and runs src with the Sealevel syscalls and default costs.
+//
+// input is mapped as the input region.
+// Logs are collected in a new LogCollector unless execution has a logger.
+func runSyscallAsm(t *testing.T, src string, execution *Execution, input []byte) (sbpf.Result, error) {
+	program, err := sbpf.Assemble(src)
+	require.NoError(t, err)
+	require.NoError(t, program.Verify(registry))
+
+	if execution.Log == nil {
+		execution.Log = new(LogCollector)
+	}
+	return sbpf.NewInterpreter(program, &sbpf.VMOpts{
+		HeapSize: MinHeapFrameBytes,
+		Syscalls: registry,
+		Costs:    DefaultCosts(),
+		MaxCU:    1_000_000,
+		Context:  execution,
+		Input:    input,
+	}).Run()
+}
+
+func TestSyscallsFor(t *testing.T) {
+	program, err := sbpf.Assemble(`
+		call sol_get_fees_sysvar
+		exit
+	`)
+	require.NoError(t, err)
+	assert.NoError(t, program.Verify(SyscallsFor(nil)))
+	assert.NoError(t, program.Verify(SyscallsFor(new(fflags.Features))))
+
+	features := new(fflags.Features).WithFeature(FeatureDisableFeesSysvar)
+	assert.Error(t, program.Verify(SyscallsFor(features)))
+	assert.Len(t, SyscallsFor(features).Hashes(), len(registry.Hashes())-1)
+}
@@ -0,0 +1,67 @@
+package sealevel
+
+import (
+	"encoding/binary"
+	"math"
+
+	"go.firedancer.io/radiance/pkg/runtime"
+)
+
+// SysvarCache holds the sysvars readable by programs via syscalls.
+//
+// Callers fill in the sysvars of the current slot.
+// A nil sysvar is not available and fails the syscall reading it.
+type SysvarCache struct {
+	Clock         *runtime.Clock
+	Rent          *runtime.RentParams
+	EpochSchedule *runtime.EpochSchedule
+	Fees          *runtime.Fees
+}
+
+// Sizes of the C layouts of sysvars in VM memory.
+// All sysvars are 8-byte aligned.
+const (
+	ClockSize         = 40
+	RentSize          = 24
+	EpochScheduleSize = 40
+	FeesSize          = 8
+
+	sysvarAlign = 8
+)
+
+func putClock(b []byte, c *runtime.Clock) {
+	binary.LittleEndian.PutUint64(b[0:8], c.Slot)
+	binary.LittleEndian.PutUint64(b[8:16], uint64(c.EpochStartTimestamp))
+	binary.LittleEndian.PutUint64(b[16:24], c.Epoch)
+	binary.LittleEndian.PutUint64(b[24:32], c.LeaderScheduleEpoch)
+	binary.LittleEndian.PutUint64(b[32:40], uint64(c.UnixTimestamp))
+}
+
+func putRent(b []byte, r *runtime.RentParams) {
+	binary.LittleEndian.PutUint64(b[0:8], r.LamportsPerByteYear)
+	binary.LittleEndian.PutUint64(b[8:16], math.Float64bits(r.ExemptionThreshold))
+	b[16] = r.BurnPercent
+	zero(b[17:24])
+}
+
+func putEpochSchedule(b []byte, e *runtime.EpochSchedule) {
+	binary.LittleEndian.PutUint64(b[0:8], e.SlotPerEpoch)
+	binary.LittleEndian.PutUint64(b[8:16], e.LeaderScheduleSlotOffset)
+	b[16] = 0
+	if e.Warmup {
+		b[16] = 1
+	}
+	zero(b[17:24])
+	binary.LittleEndian.PutUint64(b[24:32], e.FirstNormalEpoch)
+	binary.LittleEndian.PutUint64(b[32:40], e.FirstNormalSlot)
+}
+
+func putFees(b []byte, f *runtime.Fees) {
+	binary.LittleEndian.PutUint64(b[0:8], f.LamportsPerSignature)
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
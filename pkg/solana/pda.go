@@ -0,0 +1,77 @@
+package solana
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"filippo.io/edwards25519"
+)
+
+const (
+	// MaxSeeds is the maximum number of seeds of a program derived address.
+	MaxSeeds = 16
+	// MaxSeedLen is the maximum length of a single seed.
+	MaxSeedLen = 32
+)
+
+// pdaMarker is appended to the hash input of program derived addresses.
+const pdaMarker = "ProgramDerivedAddress"
+
+var (
+	ErrMaxSeedLengthExceeded = errors.New("length of the seed is too long for address generation")
+	ErrInvalidSeeds          = errors.New("provided seeds do not result in a valid address")
+)
+
+// IsOnCurve returns whether b is a valid compressed ed25519 point.
+//
+// Non-canonical encodings of valid points are considered on the curve.
+func IsOnCurve(b []byte) bool {
+	_, err := new(edwards25519.Point).SetBytes(b)
+	return err == nil
+}
+
+// CreateProgramAddress derives a program address from seeds and a program ID.
+//
+// Returns ErrInvalidSeeds if the derived address lies on the ed25519 curve,
+// since such an address could have a private key.
+func CreateProgramAddress(seeds [][]byte, programID Address) (Address, error) {
+	if len(seeds) > MaxSeeds {
+		return Address{}, ErrMaxSeedLengthExceeded
+	}
+	for _, seed := range seeds {
+		if len(seed) > MaxSeedLen {
+			return Address{}, ErrMaxSeedLengthExceeded
+		}
+	}
+	h := sha256.New()
+	for _, seed := range seeds {
+		h.Write(seed)
+	}
+	h.Write(programID[:])
+	h.Write([]byte(pdaMarker))
+	var addr Address
+	h.Sum(addr[:0])
+	if IsOnCurve(addr[:]) {
+		return Address{}, ErrInvalidSeeds
+	}
+	return addr, nil
+}
+
+// TryFindProgramAddress finds a valid program address and its bump seed.
+//
+// A single bump seed byte is appended to the seeds, counting down from 255
+// until the derived address lies off the curve.
+// Returns false if no bump seed yields a valid address.
+func TryFindProgramAddress(seeds [][]byte, programID Address) (addr Address, bump uint8, ok bool) {
+	var bumpSeed [1]byte
+	seedsWithBump := make([][]byte, len(seeds), len(seeds)+1)
+	copy(seedsWithBump, seeds)
+	seedsWithBump = append(seedsWithBump, bumpSeed[:])
+	for bump = 255; bump > 0; bump-- {
+		bumpSeed[0] = bump
+		if addr, err := CreateProgramAddress(seedsWithBump, programID); err == nil {
+			return addr, bump, true
+		}
+	}
+	return Address{}, 0, false
+}
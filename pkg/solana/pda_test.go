@@ -0,0 +1,54 @@
+package solana
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateProgramAddress(t *testing.T) {
+	programID := MustAddress("BPFLoaderUpgradeab1e11111111111111111111111")
+	pubkey := MustAddress("SeedPubey1111111111111111111111111111111111")
+
+	cases := []struct {
+		seeds [][]byte
+		addr  string
+	}{
+		{[][]byte{{}, {1}}, "BwqrghZA2htAcqq8dzP1WDAhTXYTYWj7CHxF5j7TDBAe"},
+		{[][]byte{[]byte("☉"), {0}}, "13yWmRpaTR4r5nAktwLqMpRNr28tnVUZw26rTvPSSB19"},
+		{[][]byte{[]byte("Talking"), []byte("Squirrels")}, "2fnQrngrQT4SeLcdToJAD96phoEjNL2man2kfRLCASVk"},
+		{[][]byte{pubkey[:], {1}}, "976ymqVnfE32QFe6NfGDctSvVa36LWnvYxhU6G2232YL"},
+	}
+	for _, c := range cases {
+		addr, err := CreateProgramAddress(c.seeds, programID)
+		require.NoError(t, err)
+		assert.Equal(t, c.addr, addr.String())
+	}
+
+	_, err := CreateProgramAddress([][]byte{bytes.Repeat([]byte{1}, MaxSeedLen+1)}, programID)
+	assert.ErrorIs(t, err, ErrMaxSeedLengthExceeded)
+	_, err = CreateProgramAddress(make([][]byte, MaxSeeds+1), programID)
+	assert.ErrorIs(t, err, ErrMaxSeedLengthExceeded)
+	_, err = CreateProgramAddress([][]byte{bytes.Repeat([]byte{1}, MaxSeedLen)}, programID)
+	assert.NoError(t, err)
+}
+
+func TestTryFindProgramAddress(t *testing.T) {
+	programID := MustAddress("BPFLoaderUpgradeab1e11111111111111111111111")
+	for i := 0; i < 100; i++ {
+		seed := []byte{byte(i)}
+		addr, bump, ok := TryFindProgramAddress([][]byte{seed}, programID)
+		require.True(t, ok)
+		assert.False(t, IsOnCurve(addr[:]))
+		derived, err := CreateProgramAddress([][]byte{seed, {bump}}, programID)
+		require.NoError(t, err)
+		assert.Equal(t, addr, derived)
+		// Every higher bump seed must lie on the curve
+		for b := int(bump) + 1; b <= 255; b++ {
+			_, err := CreateProgramAddress([][]byte{seed, {byte(b)}}, programID)
+			assert.ErrorIs(t, err, ErrInvalidSeeds)
+		}
+	}
+}